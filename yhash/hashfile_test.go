@@ -2,9 +2,9 @@ package yhash
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
-	"github.com/1set/gut/yos"
 	"github.com/1set/gut/ystring"
 )
 
@@ -15,16 +15,16 @@ var (
 )
 
 func init() {
-	TestCaseRootList = yos.JoinPath(os.Getenv("TESTRSSDIR"), "yhash")
+	TestCaseRootList = filepath.Join(os.Getenv("TESTRSSDIR"), "yhash")
 	FilePathMap = map[string]string{
-		"empty file":           yos.JoinPath(TestCaseRootList, "empty_file"),
-		"one-line text file":   yos.JoinPath(TestCaseRootList, "one-line_text.txt"),
-		"large text file":      yos.JoinPath(TestCaseRootList, "large_text.txt"),
-		"xlarge text file":     yos.JoinPath(TestCaseRootList, "xlarge_text.txt"),
-		"small binary file":    yos.JoinPath(TestCaseRootList, "small_file.bin"),
-		"another small binary": yos.JoinPath(TestCaseRootList, "another_small.bin"),
-		"png image":            yos.JoinPath(TestCaseRootList, "image.png"),
-		"jpg image":            yos.JoinPath(TestCaseRootList, "image.jpg"),
+		"empty file":           filepath.Join(TestCaseRootList, "empty_file"),
+		"one-line text file":   filepath.Join(TestCaseRootList, "one-line_text.txt"),
+		"large text file":      filepath.Join(TestCaseRootList, "large_text.txt"),
+		"xlarge text file":     filepath.Join(TestCaseRootList, "xlarge_text.txt"),
+		"small binary file":    filepath.Join(TestCaseRootList, "small_file.bin"),
+		"another small binary": filepath.Join(TestCaseRootList, "another_small.bin"),
+		"png image":            filepath.Join(TestCaseRootList, "image.png"),
+		"jpg image":            filepath.Join(TestCaseRootList, "image.jpg"),
 	}
 	TestFileBenchmark = FilePathMap["png image"]
 }
@@ -0,0 +1,140 @@
+package yos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+// tempNameSeq is the sequence number to generate names of temporary entries.
+var tempNameSeq uint32
+
+// ErrNotDurable indicates the target has been replaced, but the parent directory failed to be flushed, so the replacement may be lost after a crash.
+// It's wrapped in *os.PathError with the cause by AtomicFile.Commit and WriteFileAtomic.
+var ErrNotDurable = errors.New("replaced but not flushed")
+
+// AtomicFile is a file writer which replaces the target file atomically, the content is written to a temporary file in the same directory
+// and then renamed over the target after it's flushed to the disk, so the target is either the old one or the complete new one after a crash.
+//
+// Commit must be called to replace the target, and Close discards the temporary file if it's not committed, so it's safe to defer Close.
+type AtomicFile struct {
+	*os.File
+	path     string
+	mode     os.FileMode
+	keepMode bool
+	done     bool
+}
+
+// CreateAtomicFile creates a temporary file in the directory of the target path for writing, and the target will be replaced once it's committed.
+//
+// The file mode of the existing target is preserved, otherwise the new file is created with the given permission bits (before umask).
+// If the target is a symbolic link, the link itself will be replaced with a regular file.
+func CreateAtomicFile(path string, perm os.FileMode) (f *AtomicFile, err error) {
+	var fi os.FileInfo
+	f = &AtomicFile{path: path}
+	if fi, err = os.Stat(path); err == nil {
+		if isDirFi(&fi) {
+			return nil, opError(opnWrite, path, errIsDirectory)
+		}
+		f.mode, f.keepMode = fi.Mode(), true
+	} else if !os.IsNotExist(err) {
+		return nil, opError(opnWrite, path, err)
+	}
+
+	// create the temporary file exclusively, and retry with the next name if it exists
+	for {
+		temp := siblingTempPath(path, "tmp")
+		if f.File, err = os.OpenFile(temp, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm); err == nil {
+			return f, nil
+		} else if !os.IsExist(err) {
+			return nil, opError(opnWrite, path, err)
+		}
+	}
+}
+
+// Commit flushes the content to the disk, and renames the temporary file over the target, and then flushes the parent directory.
+// The temporary file is removed if it fails before renaming, and the target remains unchanged.
+// If only flushing the directory fails, the target has been replaced already, and the error wraps ErrNotDurable.
+func (f *AtomicFile) Commit() (err error) {
+	if f.done {
+		return opError(opnWrite, f.path, os.ErrClosed)
+	}
+	f.done = true
+
+	temp := f.File.Name()
+	if f.keepMode {
+		err = f.File.Chmod(f.mode)
+	}
+	if err == nil {
+		err = f.File.Sync()
+	}
+	if fe := f.File.Close(); err == nil {
+		err = fe
+	}
+	if err == nil {
+		err = os.Rename(temp, f.path)
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return opError(opnWrite, f.path, err)
+	}
+	if se := syncDir(filepath.Dir(f.path)); se != nil {
+		return opError(opnWrite, f.path, fmt.Errorf("%w: %v", ErrNotDurable, se))
+	}
+	return
+}
+
+// Close discards the temporary file if it's not committed yet, and does nothing otherwise.
+func (f *AtomicFile) Close() (err error) {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	temp := f.File.Name()
+	_ = f.File.Close()
+	if err = os.Remove(temp); err != nil {
+		err = opError(opnWrite, temp, err)
+	}
+	return
+}
+
+// WriteFileAtomic writes data to the file atomically, the file mode of the existing file is preserved, otherwise it's created with the given permission bits.
+//
+// The data is written to a temporary file in the same directory, which is flushed to the disk and renamed over the target, and then the directory is flushed as well.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	var f *AtomicFile
+	if f, err = CreateAtomicFile(path, perm); err != nil {
+		return
+	}
+	defer f.Close()
+
+	if _, err = f.Write(data); err != nil {
+		return opError(opnWrite, path, err)
+	}
+	return f.Commit()
+}
+
+// siblingTempPath returns a path of a hidden temporary entry in the same directory of the given path, with the tag as the extension.
+// The name is unique in the process, but it may exist already, so it should be created exclusively or checked.
+func siblingTempPath(path, tag string) string {
+	dir, name := filepath.Split(path)
+	seq := atomic.AddUint32(&tempNameSeq, 1)
+	return filepath.Join(dir, "."+name+"."+strconv.Itoa(os.Getpid())+"."+strconv.FormatUint(uint64(seq), 10)+"."+tag)
+}
+
+// syncDir flushes the directory entries to the disk, it's skipped on Windows since directories can't be synced there.
+func syncDir(path string) (err error) {
+	if IsOnWindows() {
+		return nil
+	}
+	var dir *os.File
+	if dir, err = os.Open(path); err != nil {
+		return
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,168 @@
+package yos
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// expectNoTempFiles checks that no temporary files of AtomicFile are left in the directory.
+func expectNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	if matches, _ := filepath.Glob(JoinPath(dir, ".*.tmp")); len(matches) > 0 {
+		t.Errorf("got temporary files left: %v", matches)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"exist.conf": "old content",
+		"dir/":       emptyStr,
+	})
+	defer os.RemoveAll(root)
+	existPath := JoinPath(root, "exist.conf")
+	if err := os.Chmod(existPath, 0640); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		perm     os.FileMode
+		wantMode os.FileMode
+		wantErr  bool
+	}{
+		{"New file", JoinPath(root, "new.conf"), 0600, 0600, false},
+		{"Existing file keeps mode", existPath, 0600, 0640, false},
+		{"Directory", JoinPath(root, "dir"), 0644, 0, true},
+		{"Missing parent", JoinPath(root, "missing", "new.conf"), 0644, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WriteFileAtomic(tt.path, []byte("new content"), tt.perm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteFileAtomic() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectNoTempFiles(t, root)
+			if err != nil {
+				expectedErrorCheck(t, err)
+				return
+			}
+			if data, err := ioutil.ReadFile(tt.path); err != nil || string(data) != "new content" {
+				t.Errorf("WriteFileAtomic() got content = %q, error = %v", data, err)
+			}
+			if fi, err := os.Stat(tt.path); err != nil || (!IsOnWindows() && fi.Mode().Perm() != tt.wantMode) {
+				t.Errorf("WriteFileAtomic() got mode = %v, error = %v, want %v", fi.Mode(), err, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestAtomicFile(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"target.txt": "original",
+	})
+	defer os.RemoveAll(root)
+	target := JoinPath(root, "target.txt")
+
+	// discarded without committing
+	f, err := CreateAtomicFile(target, 0644)
+	if err != nil {
+		t.Fatalf("CreateAtomicFile() error = %v", err)
+	}
+	if _, err = f.WriteString("partial"); err != nil {
+		t.Errorf("WriteString() error = %v", err)
+	}
+	if err = f.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	expectFileContents(t, root, map[string]string{"target.txt": "original"})
+	expectNoTempFiles(t, root)
+
+	// committed once
+	if f, err = CreateAtomicFile(target, 0644); err != nil {
+		t.Fatalf("CreateAtomicFile() error = %v", err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString("replaced"); err != nil {
+		t.Errorf("WriteString() error = %v", err)
+	}
+	if err = f.Commit(); err != nil {
+		t.Errorf("Commit() error = %v", err)
+	}
+	if err = f.Commit(); err == nil {
+		t.Errorf("Commit() got no error for committed file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	if err = f.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	expectFileContents(t, root, map[string]string{"target.txt": "replaced"})
+	expectNoTempFiles(t, root)
+}
+
+func TestCopyFileWithOptions_Atomic(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source.txt": "source content",
+		"dest.txt":   "old destination content which is longer",
+	})
+	defer os.RemoveAll(root)
+	dest := JoinPath(root, "dest.txt")
+	if err := os.Chmod(dest, 0600); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+
+	for _, strategy := range []CopyStrategy{CopyAuto, CopyBuffer} {
+		if err := CopyFileWithOptions(JoinPath(root, "source.txt"), dest, &CopyOptions{Atomic: true, Strategy: strategy}); err != nil {
+			t.Errorf("CopyFileWithOptions() error = %v", err)
+			return
+		}
+		expectFileContents(t, root, map[string]string{"dest.txt": "source content"})
+		expectNoTempFiles(t, root)
+		if fi, err := os.Stat(dest); err != nil || (!IsOnWindows() && fi.Mode().Perm() != 0600) {
+			t.Errorf("CopyFileWithOptions() got mode = %v, error = %v, want 0600", fi.Mode(), err)
+		}
+	}
+
+	newDest := JoinPath(root, "new.txt")
+	if err := CopyFileWithOptions(JoinPath(root, "source.txt"), newDest, &CopyOptions{Atomic: true, PreserveTimes: true}); err != nil {
+		t.Errorf("CopyFileWithOptions() error = %v", err)
+	}
+	expectFileContents(t, root, map[string]string{"new.txt": "source content"})
+	if err := CopyFileWithOptions(JoinPath(root, "missing.txt"), newDest, &CopyOptions{Atomic: true}); err == nil {
+		t.Errorf("CopyFileWithOptions() got no error for missing source")
+	}
+	expectFileContents(t, root, map[string]string{"new.txt": "source content"})
+	expectNoTempFiles(t, root)
+}
+
+func TestWriteFileAtomic_NotDurable(t *testing.T) {
+	if os.Geteuid() == 0 || IsOnWindows() {
+		t.Skip("permissions are not enforced for root or on Windows")
+	}
+	root := makeTestTree(t, map[string]string{
+		"dir/target.txt": "old content",
+	})
+	defer os.RemoveAll(root)
+
+	// the directory can be written but not opened for reading, so it can't be flushed after renaming
+	dir := JoinPath(root, "dir")
+	if err := os.Chmod(dir, 0300); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	err := WriteFileAtomic(JoinPath(dir, "target.txt"), []byte("new content"), 0644)
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	if !errors.Is(err, ErrNotDurable) {
+		t.Errorf("WriteFileAtomic() got error = %v, want %v", err, ErrNotDurable)
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	expectFileContents(t, root, map[string]string{"dir/target.txt": "new content"})
+	expectNoTempFiles(t, dir)
+}
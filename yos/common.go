@@ -1,6 +1,7 @@
 package yos
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -11,26 +12,36 @@ import (
 )
 
 var (
-	errInvalidPath    = errors.New("invalid path")
-	errSameFile       = errors.New("files are identical")
-	errShortRead      = errors.New("short read")
-	errIsDirectory    = errors.New("is a directory")
-	errNotDirectory   = errors.New("not a directory")
-	errNotRegularFile = errors.New("not a regular file")
-	errNotSymlink     = errors.New("not a symbolic link")
-	errStepOutDir     = errors.New("yos: step out this directory")
+	errInvalidPath         = errors.New("invalid path")
+	errSameFile            = errors.New("files are identical")
+	errShortRead           = errors.New("short read")
+	errIsDirectory         = errors.New("is a directory")
+	errNotDirectory        = errors.New("not a directory")
+	errNotRegularFile      = errors.New("not a regular file")
+	errNotSymlink          = errors.New("not a symbolic link")
+	errStepOutDir          = errors.New("yos: step out this directory")
+	errNoAvailableName     = errors.New("no available name")
+	errUnsupportedStrategy = errors.New("copy strategy not supported")
+	errWatchNotSupported   = errors.New("watching not supported")
+	errWatchOverflow       = errors.New("watch event queue overflowed")
+	errNilManifest         = errors.New("nil manifest")
 )
 
 // operation names for the Op field of os.PathError.
 var (
-	opnCompare = "compare"
-	opnCopy    = "copy"
-	opnMove    = "move"
-	opnList    = "list"
-	opnSize    = "size"
-	opnEmpty   = "empty"
-	opnChange  = "change"
-	opnMake    = "make"
+	opnCompare  = "compare"
+	opnCopy     = "copy"
+	opnMove     = "move"
+	opnList     = "list"
+	opnSize     = "size"
+	opnEmpty    = "empty"
+	opnChange   = "change"
+	opnMake     = "make"
+	opnSync     = "sync"
+	opnDedupe   = "dedupe"
+	opnManifest = "manifest"
+	opnWatch    = "watch"
+	opnWrite    = "write"
 )
 
 // internal use
@@ -51,6 +62,14 @@ func underlyingError(err error) error {
 	return err
 }
 
+// contextError returns the error of the context wrapped in *os.PathError if the context is done, otherwise returns nil.
+func contextError(ctx context.Context, op, path string) (err error) {
+	if err = ctx.Err(); err != nil {
+		err = opError(op, path, err)
+	}
+	return
+}
+
 // opError returns error struct with given details.
 func opError(op, path string, err error) *os.PathError {
 	return &os.PathError{
@@ -64,7 +83,8 @@ type (
 	funcStatFileInfo  func(name string) (os.FileInfo, error)
 	funcCheckFileInfo func(fi *os.FileInfo) bool
 	funcRemoveEntry   func(path string) error
-	funcCopyEntry     func(src, dest string) error
+	funcCopyEntry     func(t *copyTask, src, dest string) error
+	funcEntryCond     func(rel string, info os.FileInfo) (bool, error)
 )
 
 // isFileFi indicates whether the FileInfo is for a regular file.
@@ -82,6 +102,15 @@ func isSymlinkFi(fi *os.FileInfo) bool {
 	return fi != nil && ((*fi).Mode()&os.ModeType == os.ModeSymlink)
 }
 
+// nameExt returns the extension of the file name like filepath.Ext, but dot files like ".profile" are treated as names without extension.
+func nameExt(name string) string {
+	ext := filepath.Ext(name)
+	if ext == name {
+		return emptyStr
+	}
+	return ext
+}
+
 func isLinkErrorCrossDevice(err error) bool {
 	lerr, ok := err.(*os.LinkError)
 	return ok && lerr.Err == syscall.EXDEV
@@ -92,8 +121,14 @@ func isLinkErrorNotDirectory(err error) bool {
 	return ok && lerr.Err == syscall.ENOTDIR
 }
 
-// refineOpPaths validates, cleans up and adjusts the source and destination paths for operations like copy or move.
-func refineOpPaths(opName, srcRaw, destRaw string, followLink bool) (src, dest string, err error) {
+// isLinkLoopError indicates whether the error is caused by too many levels of symbolic links.
+func isLinkLoopError(err error) bool {
+	return errors.Is(err, syscall.ELOOP)
+}
+
+// refineOpPaths validates, cleans up and adjusts the source and destination paths for operations like copy or move,
+// and applies the conflict policy in the options if the final destination exists.
+func refineOpPaths(opName, srcRaw, destRaw string, followLink bool, opts *CopyOptions) (src, dest string, skip bool, err error) {
 	// validate paths, and quit if got error
 	if ystring.IsBlank(srcRaw) {
 		err = opError(opName, srcRaw, errInvalidPath)
@@ -133,6 +168,11 @@ func refineOpPaths(opName, srcRaw, destRaw string, followLink bool) (src, dest s
 			dest = JoinPath(dest, srcInfo.Name())
 		}
 	}
+
+	// apply conflict policy to the final destination
+	if err == nil && opts != nil && opts.Conflict != ConflictOverwrite {
+		dest, skip, err = resolveConflict(opName, srcInfo, dest, opts, opName == opnCopy)
+	}
 	return
 }
 
@@ -2,9 +2,11 @@ package yos
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -75,6 +77,57 @@ func unwrapErrorStruct(err error) (string, error) {
 	}
 }
 
+// makeTestTree creates a temporary directory with the given entries for tests, and returns the path of the directory.
+//
+// Keys of the entries are relative paths, a key ending with "/" stands for a directory, a value starting with "->" stands for a symbolic link to the rest of the value, otherwise the value is the content of a regular file.
+func makeTestTree(t testing.TB, entries map[string]string) string {
+	root, err := ioutil.TempDir(emptyStr, "yos-test-")
+	if err != nil {
+		t.Fatalf("fail to create temp dir: %v", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, path := entries[name], JoinPath(root, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), defaultDirectoryPermMode); err != nil {
+			break
+		}
+		switch {
+		case strings.HasSuffix(name, "/"):
+			err = os.MkdirAll(path, defaultDirectoryPermMode)
+		case strings.HasPrefix(content, "->"):
+			err = os.Symlink(filepath.FromSlash(strings.TrimPrefix(content, "->")), path)
+		default:
+			err = ioutil.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		_ = os.RemoveAll(root)
+		t.Fatalf("fail to create test tree in %q: %v", root, err)
+	}
+	return root
+}
+
+// expectFileContents checks if the files in the root directory have the expected contents.
+func expectFileContents(t *testing.T, root string, contents map[string]string) {
+	for name, want := range contents {
+		path := JoinPath(root, name)
+		if got, err := ioutil.ReadFile(path); err != nil {
+			t.Errorf("fail to read %v: %v", path, err)
+		} else if string(got) != want {
+			t.Errorf("got content of %v = %q, want %q", name, got, want)
+		}
+	}
+}
+
 func Test_opError(t *testing.T) {
 	type args struct {
 		op   string
@@ -2,14 +2,12 @@ package yos
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
-	"strings"
 )
 
 var (
-	// compareFileModeMask is a mask for file mode bits to compare in SameDirEntries.
-	compareFileModeMask = os.ModeDir | os.ModeSymlink
 	// fileCompareChunkSize represents the buffer size for readers of SameFileContent.
 	fileCompareChunkSize = 64 * 1024
 )
@@ -70,66 +68,27 @@ func SameFileContent(path1, path2 string) (same bool, err error) {
 }
 
 // SameDirEntries checks if the two directories have the same entries. Symbolic links other than the given paths will be not be followed, and only compares content of links.
-//nolint:gocyclo // Checks in this function are all necessary, no redundant checks.
+// Differences of permission bits are ignored, and DiffDirEntries can be used to find out all the differences.
 func SameDirEntries(path1, path2 string) (same bool, err error) {
-	var (
-		fi1, fi2       os.FileInfo
-		raw1, raw2     = path1, path2
-		items1, items2 []*FilePathInfo
-	)
-	// resolve paths if they're symbolic links
-	if path1, fi1, err = resolveDirInfo(path1); err != nil {
-		err = opError(opnCompare, raw1, err)
-		return
-	}
-	if path2, fi2, err = resolveDirInfo(path2); err != nil {
-		err = opError(opnCompare, raw2, err)
-		return
-	}
-
-	// quick check if it's the identical directory
-	if same = os.SameFile(fi1, fi2); same {
-		return
-	}
-
-	if items1, err = ListAll(path1); err != nil {
-		return
-	}
-	if items2, err = ListAll(path2); err != nil {
-		return
-	}
-
-	num1, num2 := len(items1), len(items2)
-	if same = num1 == num2; !same {
-		return
-	}
-
-CompareEntries:
-	for idx := 0; idx < num1; idx++ {
-		entry1, entry2 := items1[idx], items2[idx]
-
-		relativePath1, relativePath2 := strings.Replace(entry1.Path, path1, "", 1), strings.Replace(entry2.Path, path2, "", 1)
-		if same = relativePath1 == relativePath2; !same {
-			break
-		}
+	return SameDirEntriesWithOptions(path1, path2, nil)
+}
 
-		entryMode1, entryMode2 := entry1.Info.Mode(), entry2.Info.Mode()
-		if same = entryMode1&compareFileModeMask == entryMode2&compareFileModeMask; !same {
-			break
-		}
+// SameDirEntriesWithOptions checks if the two directories have the same entries with the given options. Symbolic links other than the given paths will be not be followed, and only compares content of links.
+//
+// It behaves the same as SameDirEntries if the options is nil, and the progress is reported after each pair of entries is compared.
+// Symbolic links inside the directories are followed if FollowSymlinks is set.
+func SameDirEntriesWithOptions(path1, path2 string, opts *WalkOptions) (same bool, err error) {
+	return SameDirEntriesContext(context.Background(), path1, path2, opts)
+}
 
-		switch entryMode1 & os.ModeType {
-		case os.ModeSymlink:
-			if same, err = SameSymlinkContent(entry1.Path, entry2.Path); err != nil || !same {
-				break CompareEntries
-			}
-		case os.ModeDir:
-			// ignore the directory structure here, since it's already compared by the relative path logic before
-		case 0:
-			if same, err = SameFileContent(entry1.Path, entry2.Path); err != nil || !same {
-				break CompareEntries
-			}
-		}
+// SameDirEntriesContext checks if the two directories have the same entries with the given options and context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+// It stops at the first difference found, so the remaining entries are neither compared nor reported in the progress.
+func SameDirEntriesContext(ctx context.Context, path1, path2 string, opts *WalkOptions) (same bool, err error) {
+	var diff *DirDiff
+	if diff, err = diffDirEntries(ctx, path1, path2, opts, true); err == nil {
+		same = diff.sameEntries()
 	}
 	return
 }
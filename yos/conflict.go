@@ -0,0 +1,105 @@
+package yos
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConflictPolicy indicates how copy and move operations handle an existing destination.
+type ConflictPolicy int
+
+// The policies are used by the Conflict field of CopyOptions and MoveOptions.
+const (
+	// ConflictOverwrite indicates to overwrite the existing destination, it's the default policy.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictFail indicates to return an error if the destination exists.
+	ConflictFail
+	// ConflictSkip indicates to skip the source if the destination exists.
+	ConflictSkip
+	// ConflictUpdate indicates to overwrite the existing destination only if the source is newer, otherwise skip it.
+	ConflictUpdate
+	// ConflictBackup indicates to rename the existing destination with the backup suffix before overwriting it.
+	ConflictBackup
+	// ConflictRename indicates to use an available name like "name (1).ext" for the destination instead.
+	ConflictRename
+)
+
+const (
+	// defaultBackupSuffix is the suffix for backup files if it's not specified in the options.
+	defaultBackupSuffix = "~"
+	// maxRenameAttempts is the limit of attempts to find an available name for ConflictRename.
+	maxRenameAttempts = 10000
+)
+
+// resolveConflict applies the conflict policy to the destination if it exists, and returns the final destination or indicates to skip the source.
+//
+// Directories are merged instead of being overwritten or updated if mergeDir is set and both the source and destination are directories.
+func resolveConflict(opName string, srcInfo os.FileInfo, dest string, opts *CopyOptions, mergeDir bool) (target string, skip bool, err error) {
+	var destInfo os.FileInfo
+	if destInfo, err = os.Lstat(dest); err != nil {
+		// no conflicts if the destination doesn't exist
+		if os.IsNotExist(err) {
+			target, err = dest, nil
+		} else {
+			err = opError(opName, dest, err)
+		}
+		return
+	}
+
+	switch opts.Conflict {
+	case ConflictFail:
+		err = opError(opName, dest, os.ErrExist)
+	case ConflictSkip:
+		skip = true
+	case ConflictUpdate:
+		if (mergeDir && srcInfo.IsDir() && destInfo.IsDir()) || srcInfo.ModTime().After(destInfo.ModTime()) {
+			target = dest
+		} else {
+			skip = true
+		}
+	case ConflictBackup:
+		suffix := opts.BackupSuffix
+		if suffix == emptyStr {
+			suffix = defaultBackupSuffix
+		}
+		if err = backupEntry(dest, dest+suffix); err == nil {
+			target = dest
+		} else {
+			err = opError(opName, dest, err)
+		}
+	case ConflictRename:
+		if target, err = availableName(dest); err != nil {
+			err = opError(opName, dest, err)
+		}
+	default:
+		target = dest
+	}
+	return
+}
+
+// backupEntry renames the entry to the backup path, and the previous backup will be replaced.
+func backupEntry(path, backupPath string) (err error) {
+	if err = os.RemoveAll(backupPath); err == nil {
+		err = os.Rename(path, backupPath)
+	}
+	return
+}
+
+// availableName returns the first non-existent path like "name (1).ext" for the given path.
+func availableName(path string) (string, error) {
+	dir, name := filepath.Split(path)
+	ext := nameExt(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := dir + base + " (" + strconv.Itoa(i) + ")" + ext
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		} else if err != nil {
+			return emptyStr, err
+		}
+	}
+	return emptyStr, errNoAvailableName
+}
@@ -0,0 +1,179 @@
+package yos
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCopyFileWithOptions_Conflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        *CopyOptions
+		destNewer   bool
+		wantErr     bool
+		wantContent map[string]string
+	}{
+		{"Overwrite by default", nil, false, false, map[string]string{"dest.txt": "source"}},
+		{"Overwrite explicitly", &CopyOptions{Conflict: ConflictOverwrite}, false, false, map[string]string{"dest.txt": "source"}},
+		{"Fail if exists", &CopyOptions{Conflict: ConflictFail}, false, true, map[string]string{"dest.txt": "destination"}},
+		{"Skip if exists", &CopyOptions{Conflict: ConflictSkip}, false, false, map[string]string{"dest.txt": "destination"}},
+		{"Update if source is newer", &CopyOptions{Conflict: ConflictUpdate}, false, false, map[string]string{"dest.txt": "source"}},
+		{"Skip update if destination is newer", &CopyOptions{Conflict: ConflictUpdate}, true, false, map[string]string{"dest.txt": "destination"}},
+		{"Backup with default suffix", &CopyOptions{Conflict: ConflictBackup}, false, false, map[string]string{"dest.txt": "source", "dest.txt~": "destination"}},
+		{"Backup with custom suffix", &CopyOptions{Conflict: ConflictBackup, BackupSuffix: ".bak"}, false, false, map[string]string{"dest.txt": "source", "dest.txt.bak": "destination"}},
+		{"Rename the destination", &CopyOptions{Conflict: ConflictRename}, false, false, map[string]string{"dest.txt": "destination", "dest (1).txt": "existing", "dest (2).txt": "source"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"source.txt":   "source",
+				"dest.txt":     "destination",
+				"dest (1).txt": "existing",
+			})
+			defer os.RemoveAll(root)
+
+			srcPath, destPath := JoinPath(root, "source.txt"), JoinPath(root, "dest.txt")
+			oldTime, newTime := time.Now().Add(-time.Hour), time.Now()
+			if tt.destNewer {
+				oldTime, newTime = newTime, oldTime
+			}
+			_ = os.Chtimes(destPath, oldTime, oldTime)
+			_ = os.Chtimes(srcPath, newTime, newTime)
+
+			err := CopyFileWithOptions(srcPath, destPath, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CopyFileWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			if tt.wantErr && !os.IsExist(err) {
+				t.Errorf("CopyFileWithOptions() got error = %v, want existence error", err)
+			}
+			expectFileContents(t, root, tt.wantContent)
+		})
+	}
+}
+
+func TestCopyDirWithOptions_Conflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        *CopyOptions
+		wantErr     bool
+		wantContent map[string]string
+		wantMissing []string
+	}{
+		{"Merge and overwrite", &CopyOptions{Conflict: ConflictOverwrite}, false, map[string]string{"out/src/a.txt": "new a", "out/src/sub/b.txt": "new b", "out/src/sub/c.txt": "old c"}, nil},
+		{"Fail for existing directory", &CopyOptions{Conflict: ConflictFail}, true, map[string]string{"out/src/a.txt": "old a", "out/src/sub/c.txt": "old c"}, []string{"out/src/sub/b.txt"}},
+		{"Skip existing directory", &CopyOptions{Conflict: ConflictSkip}, false, map[string]string{"out/src/a.txt": "old a", "out/src/sub/c.txt": "old c"}, []string{"out/src/sub/b.txt"}},
+		{"Merge and update newer files", &CopyOptions{Conflict: ConflictUpdate}, false, map[string]string{"out/src/a.txt": "old a", "out/src/sub/b.txt": "new b", "out/src/sub/c.txt": "old c"}, nil},
+		{"Backup existing directory", &CopyOptions{Conflict: ConflictBackup}, false, map[string]string{"out/src/a.txt": "new a", "out/src/sub/b.txt": "new b", "out/src~/a.txt": "old a", "out/src~/sub/c.txt": "old c"}, []string{"out/src/sub/c.txt", "out/src~/sub/b.txt"}},
+		{"Rename the destination directory", &CopyOptions{Conflict: ConflictRename}, false, map[string]string{"out/src/a.txt": "old a", "out/src/sub/c.txt": "old c", "out/src (1)/a.txt": "new a", "out/src (1)/sub/b.txt": "new b"}, []string{"out/src/sub/b.txt", "out/src (1)/sub/c.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"src/a.txt":         "new a",
+				"src/sub/b.txt":     "new b",
+				"out/src/a.txt":     "old a",
+				"out/src/sub/c.txt": "old c",
+			})
+			defer os.RemoveAll(root)
+
+			// make the existing a.txt newer than the source
+			future := time.Now().Add(time.Hour)
+			_ = os.Chtimes(JoinPath(root, "out", "src", "a.txt"), future, future)
+
+			err := CopyDirWithOptions(JoinPath(root, "src"), JoinPath(root, "out"), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CopyDirWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			expectFileContents(t, root, tt.wantContent)
+			// the whole directory is handled unless it's merged
+			for _, rel := range tt.wantMissing {
+				if _, err := os.Lstat(JoinPath(root, rel)); !os.IsNotExist(err) {
+					t.Errorf("CopyDirWithOptions() got %s, want it missing, error = %v", rel, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMoveWithOptions_Conflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		move        func(src, dest string, opts *MoveOptions) error
+		src         string
+		opts        *MoveOptions
+		wantErr     bool
+		wantSrc     bool
+		wantContent map[string]string
+	}{
+		{"File: overwrite", MoveFileWithOptions, "file.txt", nil, false, false, map[string]string{"out/file.txt": "new"}},
+		{"File: fail if exists", MoveFileWithOptions, "file.txt", &MoveOptions{CopyOptions{Conflict: ConflictFail}}, true, true, map[string]string{"out/file.txt": "old"}},
+		{"File: skip if exists", MoveFileWithOptions, "file.txt", &MoveOptions{CopyOptions{Conflict: ConflictSkip}}, false, true, map[string]string{"out/file.txt": "old"}},
+		{"File: backup existing", MoveFileWithOptions, "file.txt", &MoveOptions{CopyOptions{Conflict: ConflictBackup, BackupSuffix: ".orig"}}, false, false, map[string]string{"out/file.txt": "new", "out/file.txt.orig": "old"}},
+		{"File: rename destination", MoveFileWithOptions, "file.txt", &MoveOptions{CopyOptions{Conflict: ConflictRename}}, false, false, map[string]string{"out/file.txt": "old", "out/file (1).txt": "new"}},
+		{"Dir: overwrite", MoveDirWithOptions, "dir", nil, false, false, map[string]string{"out/dir/new.txt": "new"}},
+		{"Dir: fail if exists", MoveDirWithOptions, "dir", &MoveOptions{CopyOptions{Conflict: ConflictFail}}, true, true, map[string]string{"out/dir/old.txt": "old"}},
+		{"Dir: skip if exists", MoveDirWithOptions, "dir", &MoveOptions{CopyOptions{Conflict: ConflictSkip}}, false, true, map[string]string{"out/dir/old.txt": "old"}},
+		{"Dir: backup existing", MoveDirWithOptions, "dir", &MoveOptions{CopyOptions{Conflict: ConflictBackup}}, false, false, map[string]string{"out/dir/new.txt": "new", "out/dir~/old.txt": "old"}},
+		{"Dir: rename destination", MoveDirWithOptions, "dir", &MoveOptions{CopyOptions{Conflict: ConflictRename}}, false, false, map[string]string{"out/dir/old.txt": "old", "out/dir (1)/new.txt": "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"file.txt":        "new",
+				"dir/new.txt":     "new",
+				"out/file.txt":    "old",
+				"out/dir/old.txt": "old",
+			})
+			defer os.RemoveAll(root)
+
+			srcPath := JoinPath(root, tt.src)
+			err := tt.move(srcPath, JoinPath(root, "out"), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Move*WithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			if gotSrc := Exist(srcPath); gotSrc != tt.wantSrc {
+				t.Errorf("Move*WithOptions() got source existence = %v, want %v", gotSrc, tt.wantSrc)
+			}
+			expectFileContents(t, root, tt.wantContent)
+		})
+	}
+}
+
+func Test_availableName(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"text.txt":     "0",
+		"text (1).txt": "1",
+		".profile":     "0",
+		"archive/":     emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"File with extension", "text.txt", "text (2).txt"},
+		{"Dot file", ".profile", ".profile (1)"},
+		{"Directory", "archive", "archive (1)"},
+		{"Missing file", "missing.md", "missing (1).md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := availableName(JoinPath(root, tt.path))
+			if err != nil {
+				t.Errorf("availableName() error = %v", err)
+			} else if want := JoinPath(root, tt.want); got != want {
+				t.Errorf("availableName() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
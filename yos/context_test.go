@@ -0,0 +1,114 @@
+package yos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContextCanceled(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":     "a",
+		"source/sub/b.txt": "b",
+		"source/sub/c/":    emptyStr,
+		"other/a.txt":      "a",
+		"other/sub/b.txt":  "b",
+		"other/sub/c/":     emptyStr,
+		"file.txt":         "file",
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context) error
+		cleaned string
+	}{
+		{"ListAllContext", func(ctx context.Context) error { _, err := ListAllContext(ctx, srcRoot); return err }, emptyStr},
+		{"ListFileContext", func(ctx context.Context) error { _, err := ListFileContext(ctx, srcRoot); return err }, emptyStr},
+		{"ListDirContext", func(ctx context.Context) error { _, err := ListDirContext(ctx, srcRoot); return err }, emptyStr},
+		{"ListSymlinkContext", func(ctx context.Context) error { _, err := ListSymlinkContext(ctx, srcRoot); return err }, emptyStr},
+		{"ListMatchContext", func(ctx context.Context) error {
+			_, err := ListMatchContext(ctx, srcRoot, ListIncludeAll|ListRecursive, "*")
+			return err
+		}, emptyStr},
+		{"GetDirSizeContext", func(ctx context.Context) error { _, err := GetDirSizeContext(ctx, srcRoot, nil); return err }, emptyStr},
+		{"IsDirEmptyContext", func(ctx context.Context) error { _, err := IsDirEmptyContext(ctx, srcRoot); return err }, emptyStr},
+		{"SameDirEntriesContext", func(ctx context.Context) error {
+			_, err := SameDirEntriesContext(ctx, srcRoot, JoinPath(root, "other"), nil)
+			return err
+		}, emptyStr},
+		{"CopyFileContext", func(ctx context.Context) error {
+			return CopyFileContext(ctx, JoinPath(root, "file.txt"), JoinPath(root, "copied.txt"), nil)
+		}, "copied.txt"},
+		{"CopyDirContext", func(ctx context.Context) error {
+			return CopyDirContext(ctx, srcRoot, JoinPath(root, "copied"), nil)
+		}, "copied"},
+		{"MoveFileContext", func(ctx context.Context) error {
+			return MoveFileContext(ctx, JoinPath(root, "file.txt"), JoinPath(root, "moved.txt"), nil)
+		}, "moved.txt"},
+		{"MoveDirContext", func(ctx context.Context) error {
+			return MoveDirContext(ctx, srcRoot, JoinPath(root, "moved"), nil)
+		}, "moved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%s() got error = %v, want context canceled", tt.name, err)
+				return
+			}
+			expectedErrorCheck(t, err)
+			if tt.cleaned != emptyStr && Exist(JoinPath(root, tt.cleaned)) {
+				t.Errorf("%s() fail to clean up the destination: %v", tt.name, tt.cleaned)
+			}
+			if err = tt.run(context.Background()); err != nil {
+				t.Errorf("%s() got error = %v for uncanceled context", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestCopyDirContext_CancelInProgress(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":     strings.Repeat("a", defaultBufferSize*2),
+		"source/sub/b.txt": "b",
+		"source/sub/c.txt": "c",
+		"exist/":           emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name     string
+		dest     string
+		wantLeft string
+	}{
+		{"Destination doesn't exist", JoinPath(root, "copied"), JoinPath(root, "copied")},
+		{"Destination is an existing directory", JoinPath(root, "exist"), JoinPath(root, "exist", "source")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// cancel after the first chunk is written
+			opts := &CopyOptions{Progress: func(p Progress) { cancel() }}
+			err := CopyDirContext(ctx, JoinPath(root, "source"), tt.dest, opts)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("CopyDirContext() got error = %v, want context canceled", err)
+				return
+			}
+			if Exist(tt.wantLeft) {
+				t.Errorf("CopyDirContext() fail to clean up the destination: %v", tt.wantLeft)
+			}
+			if !ExistDir(JoinPath(root, "source", "sub")) {
+				t.Errorf("CopyDirContext() source is changed")
+			}
+		})
+	}
+}
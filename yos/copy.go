@@ -1,6 +1,9 @@
 package yos
 
 import (
+	"context"
+	"crypto/sha256"
+	"hash"
 	"io"
 	"io/ioutil"
 	"math/bits"
@@ -13,6 +16,67 @@ const (
 	defaultNewFileFlag       = os.O_RDWR | os.O_CREATE | os.O_TRUNC
 )
 
+// CopyOptions represents the options for copy operations. The zero value or nil indicates the default behavior.
+type CopyOptions struct {
+	// PreserveTimes indicates whether to preserve the modification and access times of copied entries.
+	PreserveTimes bool
+	// PreserveOwner indicates whether to preserve the user and group ownership of copied entries, it will be skipped silently if not permitted.
+	PreserveOwner bool
+	// PreserveXattrs indicates whether to preserve the extended attributes of copied entries, it's only supported on Linux for now.
+	PreserveXattrs bool
+	// Conflict indicates how to handle the existing destination. For an existing directory, ConflictOverwrite and ConflictUpdate merge entries into it
+	// and apply the same policy to each nested entry, while ConflictFail, ConflictSkip, ConflictBackup and ConflictRename act on the whole directory,
+	// i.e. return an error, skip the source directory, rename the existing one with the backup suffix, or copy to an available name instead.
+	Conflict ConflictPolicy
+	// BackupSuffix is the suffix appended to the name of the existing destination for ConflictBackup, "~" is used if it's empty.
+	BackupSuffix string
+	// Progress is called while copying content of files and after each entry is copied if it's not nil.
+	Progress ProgressFunc
+	// Workers is the number of goroutines to copy files and symbolic links inside directories concurrently, entries are copied one by one if it's less than 2.
+	Workers int
+	// Strategy indicates how to copy content of files, CopyAuto is the default.
+	Strategy CopyStrategy
+	// Sparse indicates whether to skip holes and blocks of zeros in files to keep the destination sparse, it works with CopyAuto and CopyBuffer.
+	Sparse bool
+	// PreserveHardlinks indicates whether to recreate hard links among files inside directories instead of copying the content again, it's not supported on Windows.
+	PreserveHardlinks bool
+	// Exclude holds rules in .gitignore syntax relative to the source directory to exclude entries from copying directories.
+	Exclude []string
+	// IgnoreFileName is the name of ignore files like ".gitignore", rules in such files found in the source directory and nested directories are applied as well if it's not empty.
+	IgnoreFileName string
+	// ContinueOnError indicates whether to skip entries that fail to copy and continue copying directories, errors are collected and returned as PathErrors.
+	ContinueOnError bool
+	// Atomic indicates whether to write each file to a temporary file in the same directory and rename it over the destination after it's flushed,
+	// so the existing destination is never left truncated or partially written.
+	Atomic bool
+	// Verify indicates whether to hash the source with SHA-256 while copying each file, and compare it with the digest of the destination re-read after it's flushed.
+	// The bad copy is removed and an error wrapping ErrChecksumMismatch is returned if they're different.
+	Verify bool
+	// Resume indicates whether to continue copying files from the end of partial destinations instead of truncating them, and partial destinations are kept on errors.
+	// The copied prefix is checked by its size and its tail block compared with the source, and the file is copied from the start if they differ.
+	// The rest of the prefix is trusted, so Verify should be set to detect other changes. It's ignored if Atomic is set.
+	// The rest of content is always copied with the buffer, so Strategy is ignored and holes are not kept even if Sparse is set.
+	Resume bool
+}
+
+// CopyStrategy indicates how to copy content of files.
+type CopyStrategy int
+
+// The strategies are used by the Strategy field of CopyOptions. Strategies other than CopyAuto and CopyBuffer are only supported on Linux,
+// and an error will be returned if the forced strategy is not supported by the platform or file systems.
+const (
+	// CopyAuto indicates to try CopyReflink, CopyFileRange and CopySendfile in order, and fall back to CopyBuffer if none of them works.
+	CopyAuto CopyStrategy = iota
+	// CopyBuffer indicates to read and write content with a buffer in user space.
+	CopyBuffer
+	// CopyReflink indicates to clone the file with FICLONE ioctl, the destination shares the data blocks with the source until it's modified, e.g. on Btrfs and XFS.
+	CopyReflink
+	// CopyFileRange indicates to copy content in the kernel with copy_file_range system call.
+	CopyFileRange
+	// CopySendfile indicates to copy content in the kernel with sendfile system call.
+	CopySendfile
+)
+
 // CopyFile copies a file to a target file or directory. Symbolic links are followed.
 //
 // If the target is an existing file, the target will be overwritten with the source file.
@@ -23,8 +87,25 @@ const (
 //
 // If there is an error, it will be of type *os.PathError.
 func CopyFile(src, dest string) (err error) {
-	if src, dest, err = refineOpPaths(opnCopy, src, dest, true); err == nil {
-		err = bufferCopyFile(src, dest, defaultBufferSize)
+	return CopyFileWithOptions(src, dest, nil)
+}
+
+// CopyFileWithOptions copies a file to a target file or directory with the given options. Symbolic links are followed.
+//
+// It behaves the same as CopyFile if the options is nil.
+func CopyFileWithOptions(src, dest string, opts *CopyOptions) (err error) {
+	return CopyFileContext(context.Background(), src, dest, opts)
+}
+
+// CopyFileContext copies a file to a target file or directory with the given options and context. Symbolic links are followed.
+//
+// It checks the context while copying the content, and returns the error of the context wrapped in *os.PathError if it's done, and the partial destination file will be removed.
+func CopyFileContext(ctx context.Context, src, dest string, opts *CopyOptions) (err error) {
+	var skip bool
+	if src, dest, skip, err = refineOpPaths(opnCopy, src, dest, true, opts); err == nil && !skip {
+		t := newCopyTask(ctx, opnCopy, opts)
+		t.estimateTotal(src, os.Stat)
+		err = t.copyFile(src, dest)
 	}
 	return
 }
@@ -39,8 +120,29 @@ func CopyFile(src, dest string) (err error) {
 //
 // It stops and returns immediately if any error occurs, and the error will be of type *os.PathError.
 func CopyDir(src, dest string) (err error) {
-	if src, dest, err = refineOpPaths(opnCopy, src, dest, true); err == nil {
-		err = copyDir(src, dest)
+	return CopyDirWithOptions(src, dest, nil)
+}
+
+// CopyDirWithOptions copies a directory to a target directory recursively with the given options. Symbolic links inside the directories will be copied instead of being followed.
+//
+// It behaves the same as CopyDir if the options is nil, and the options are applied to all nested files, directories and symbolic links.
+func CopyDirWithOptions(src, dest string, opts *CopyOptions) (err error) {
+	return CopyDirContext(context.Background(), src, dest, opts)
+}
+
+// CopyDirContext copies a directory to a target directory recursively with the given options and context. Symbolic links inside the directories will be copied instead of being followed.
+//
+// It checks the context between entries and while copying the content of files, and returns the error of the context wrapped in *os.PathError if it's done,
+// and the directories created by the operation will be removed.
+func CopyDirContext(ctx context.Context, src, dest string, opts *CopyOptions) (err error) {
+	var skip bool
+	if src, dest, skip, err = refineOpPaths(opnCopy, src, dest, true, opts); err == nil && !skip {
+		t := newCopyTask(ctx, opnCopy, opts)
+		if t.exclude, err = t.newExcludeMatcher(src); err != nil {
+			return
+		}
+		t.estimateTotal(src, os.Stat)
+		err = t.errs.result(t.copyTree(src, dest))
 	}
 	return
 }
@@ -51,15 +153,105 @@ func CopyDir(src, dest string) (err error) {
 //
 // If there is an error, it will be of type *os.PathError.
 func CopySymlink(src, dest string) (err error) {
-	if src, dest, err = refineOpPaths(opnCopy, src, dest, false); err == nil {
-		err = copySymlink(src, dest)
+	return CopySymlinkWithOptions(src, dest, nil)
+}
+
+// CopySymlinkWithOptions copies a symbolic link to a target file with the given options.
+//
+// It behaves the same as CopySymlink if the options is nil.
+func CopySymlinkWithOptions(src, dest string, opts *CopyOptions) (err error) {
+	var skip bool
+	if src, dest, skip, err = refineOpPaths(opnCopy, src, dest, false, opts); err == nil && !skip {
+		t := newCopyTask(context.Background(), opnCopy, opts)
+		t.estimateTotal(src, os.Lstat)
+		err = t.copySymlink(src, dest)
 	}
 	return
 }
 
-// bufferCopyFile reads content from the source file and write to the destination file with a buffer.
+// copyTask holds the context, options and progress shared by all steps of a copy operation.
+type copyTask struct {
+	ctx      context.Context
+	opts     CopyOptions
+	progress *progressTracker
+	plan     *copyPlan
+	links    map[fileInode]string
+	exclude  *excludeMatcher
+	errs     errorCollector
+}
+
+// newCopyTask returns a copy task for the operation with the given context and options, nil options indicates the default.
+func newCopyTask(ctx context.Context, opName string, opts *CopyOptions) *copyTask {
+	t := &copyTask{ctx: ctx}
+	if opts != nil {
+		t.opts = *opts
+	}
+	t.progress = newProgressTracker(opName, t.opts.Progress)
+	return t
+}
+
+// newExcludeMatcher returns a matcher with the exclusion rules in options for the source directory, or nil if there are no rules.
+func (t *copyTask) newExcludeMatcher(src string) (*excludeMatcher, error) {
+	return newExcludeMatcher(opnCopy, src, t.opts.Exclude, t.opts.IgnoreFileName)
+}
+
+// estimateTotal sets the total numbers of bytes and entries to copy for the progress, errors are ignored since it's just an estimate.
+func (t *copyTask) estimateTotal(src string, stat funcStatFileInfo) {
+	if t.progress == nil {
+		return
+	}
+
+	var bytes, files int64
+	if fi, err := stat(src); err == nil && isDirFi(&fi) {
+		// walk with another matcher to leave the one for copying untouched
+		opts := &WalkOptions{Exclude: t.opts.Exclude, IgnoreFileName: t.opts.IgnoreFileName, ContinueOnError: true}
+		_ = walkOpEntries(t.ctx, opnCopy, src, opts, func(string, os.FileInfo) (bool, error) { return true, nil }, func(entry *FilePathInfo) error {
+			if info := entry.Info; !isDirFi(&info) {
+				if isFileFi(&info) {
+					bytes += info.Size()
+				}
+				files++
+			}
+			return nil
+		})
+	} else if err == nil {
+		if isFileFi(&fi) {
+			bytes = fi.Size()
+		}
+		files = 1
+	}
+	t.progress.setTotal(bytes, files)
+}
+
+// copyFile copies content of the source file to the destination file, and then applies the metadata.
+func (t *copyTask) copyFile(src, dest string) (err error) {
+	// check before opening the destination to avoid truncating it for nothing
+	if err = contextError(t.ctx, opnCopy, src); err != nil {
+		return
+	}
+	if err = t.bufferCopyFile(src, dest, defaultBufferSize); err == nil {
+		err = t.preserveMetadata(src, dest, os.Stat)
+	}
+	if err == nil {
+		t.progress.addFile(src, 0)
+	}
+	return
+}
+
+// copySymlink copies the source symbolic link to the destination, and then applies the metadata.
+func (t *copyTask) copySymlink(src, dest string) (err error) {
+	if err = copySymlink(src, dest); err == nil {
+		err = t.preserveMetadata(src, dest, os.Lstat)
+	}
+	if err == nil {
+		t.progress.addFile(src, 0)
+	}
+	return
+}
+
+// bufferCopyFile reads content from the source file and write to the destination file with a buffer, and reports the bytes written to the progress tracker.
 //nolint:gocyclo // buffer copy is a complicated thing indeed.
-func bufferCopyFile(src, dest string, bufferSize int64) (err error) {
+func (t *copyTask) bufferCopyFile(src, dest string, bufferSize int64) (err error) {
 	var (
 		srcFile, destFile *os.File
 		srcInfo, destInfo os.FileInfo
@@ -88,10 +280,14 @@ func bufferCopyFile(src, dest string, bufferSize int64) (err error) {
 		return
 	}
 
-	// use smaller buffer if source file is not big enough
-	fileSize := srcInfo.Size()
-	if bufferSize > fileSize {
-		bufferSize = 1 << uint(bits.Len64(uint64(fileSize)))
+	var sum hash.Hash
+	if t.opts.Verify {
+		sum = sha256.New()
+	}
+	if t.opts.Atomic {
+		return t.atomicCopyFile(srcFile, src, dest, srcInfo, bufferSize, sum)
+	} else if t.opts.Resume {
+		return t.resumeCopyFile(srcFile, src, dest, srcInfo, bufferSize, sum)
 	}
 
 	if destFile, err = os.OpenFile(dest, defaultNewFileFlag, srcInfo.Mode()); err != nil {
@@ -107,9 +303,78 @@ func bufferCopyFile(src, dest string, bufferSize int64) (err error) {
 		}
 	}()
 
+	if err = t.copyContent(srcFile, destFile, src, dest, srcInfo.Size(), bufferSize, sum); err == nil && sum != nil {
+		err = verifyCopiedFile(destFile, dest, sum)
+	}
+
+	// err = destFile.Sync()
+	return
+}
+
+// atomicCopyFile copies content of the source file to a temporary file, and then replaces the destination with it atomically.
+func (t *copyTask) atomicCopyFile(srcFile *os.File, src, dest string, srcInfo os.FileInfo, bufferSize int64, sum hash.Hash) (err error) {
+	var destFile *AtomicFile
+	if destFile, err = CreateAtomicFile(dest, srcInfo.Mode()); err != nil {
+		return
+	}
+	defer destFile.Close()
+
+	if err = t.copyContent(srcFile, destFile.File, src, dest, srcInfo.Size(), bufferSize, sum); err == nil && sum != nil {
+		err = verifyCopiedFile(destFile.File, dest, sum)
+	}
+	if err == nil {
+		err = destFile.Commit()
+	}
+	return
+}
+
+// copyContent copies content from the source file to the destination file with the strategy in options, and writes the content read to the hash if it's not nil.
+func (t *copyTask) copyContent(srcFile, destFile *os.File, src, dest string, size, bufferSize int64, sum hash.Hash) (err error) {
+	var handled bool
+	switch strategy := t.opts.Strategy; strategy {
+	case CopyAuto:
+		// try kernel strategies in order, and fall back to the buffer if none of them works, only reflink keeps holes for sparse files
+		strategies := []CopyStrategy{CopyReflink, CopyFileRange, CopySendfile}
+		if t.opts.Sparse {
+			strategies = strategies[:1]
+		}
+		for _, ks := range strategies {
+			if handled, err = t.kernelCopyContent(ks, srcFile, destFile, src, dest, size); handled || err != nil {
+				if handled && err == nil && sum != nil {
+					err = hashCopiedContent(sum, srcFile, destFile, src, dest)
+				}
+				return
+			}
+		}
+	case CopyBuffer:
+	default:
+		if handled, err = t.kernelCopyContent(strategy, srcFile, destFile, src, dest, size); !handled && err == nil {
+			err = opError(opnCopy, dest, errUnsupportedStrategy)
+		} else if err == nil && sum != nil {
+			err = hashCopiedContent(sum, srcFile, destFile, src, dest)
+		}
+		return
+	}
+
+	if t.opts.Sparse {
+		return t.sparseCopyContent(srcFile, destFile, src, dest, size, bufferSize, sum)
+	}
+
+	// use smaller buffer if source file is not big enough
+	if bufferSize > size {
+		bufferSize = 1 << uint(bits.Len64(uint64(size)))
+	}
+	return t.bufferCopyContent(srcFile, destFile, src, dest, bufferSize, sum)
+}
+
+// bufferCopyContent reads content from the source file and write to the destination file with a buffer, and writes the content read to the hash if it's not nil.
+func (t *copyTask) bufferCopyContent(srcFile, destFile *os.File, src, dest string, bufferSize int64, sum hash.Hash) (err error) {
 	var nr, nw int
 	buf := make([]byte, bufferSize)
 	for {
+		if err = contextError(t.ctx, opnCopy, src); err != nil {
+			break
+		}
 		if nr, err = srcFile.Read(buf); err != nil || nr == 0 {
 			if err == io.EOF && nr > 0 {
 				err = opError(opnCopy, src, io.ErrUnexpectedEOF)
@@ -117,19 +382,21 @@ func bufferCopyFile(src, dest string, bufferSize int64) (err error) {
 			break
 		}
 
+		if sum != nil {
+			sum.Write(buf[:nr])
+		}
 		if nw, err = destFile.Write(buf[:nr]); err != nil {
 			break
 		} else if nw != nr {
 			err = opError(opnCopy, dest, io.ErrShortWrite)
 			break
 		}
+		t.progress.addBytes(src, int64(nw))
 	}
 
 	if err == io.EOF {
 		err = nil
 	}
-
-	// err = destFile.Sync()
 	return
 }
 
@@ -163,7 +430,7 @@ func copySymlink(src, dest string) (err error) {
 
 // copyDir copies all entries of source directory to destination directory recursively.
 //nolint:gocyclo // copy directory refers itself with copy file and copy symlink, it's hard to reduce the complexity.
-func copyDir(src, dest string) (err error) {
+func (t *copyTask) copyDir(src, dest string) (err error) {
 	var srcInfo, destInfo os.FileInfo
 
 	// check if source exists and is a directory
@@ -187,7 +454,14 @@ func copyDir(src, dest string) (err error) {
 		err = nil
 		if err = os.MkdirAll(dest, defaultDirectoryPermMode); err == nil {
 			originMode := srcInfo.Mode()
-			defer os.Chmod(dest, originMode)
+			if t.plan != nil {
+				// finish the directory after all planned entries are copied
+				t.plan.dirs = append(t.plan.dirs, createdDir{src: src, dest: dest, mode: originMode})
+			} else {
+				defer func() {
+					err = t.finishDir(src, dest, originMode, err)
+				}()
+			}
 		}
 	}
 	if err != nil {
@@ -199,26 +473,95 @@ func copyDir(src, dest string) (err error) {
 	if entries, err = ioutil.ReadDir(src); err != nil {
 		return
 	}
+	if err = t.exclude.loadDir(src); err != nil {
+		return
+	}
 
 IterateEntry:
 	for _, entry := range entries {
 		srcPath, destPath := JoinPath(src, entry.Name()), JoinPath(dest, entry.Name())
+		if err = contextError(t.ctx, opnCopy, srcPath); err != nil {
+			break
+		}
+		if t.exclude.excluded(srcPath, entry) {
+			continue
+		}
 
-		switch entry.Mode() & os.ModeType {
-		case os.ModeDir:
-			if err = copyDir(srcPath, destPath); err != nil {
-				break IterateEntry
-			}
-		case os.ModeSymlink:
-			if err = copySymlink(srcPath, destPath); err != nil {
+		// apply conflict policy to nested entries
+		if t.opts.Conflict != ConflictOverwrite {
+			var skip bool
+			if destPath, skip, err = resolveConflict(opnCopy, entry, destPath, &t.opts, true); err != nil {
 				break IterateEntry
+			} else if skip {
+				continue
 			}
-		case 0:
-			if err = bufferCopyFile(srcPath, destPath, defaultBufferSize); err != nil {
-				break IterateEntry
+		}
+
+		switch entry.Mode() & os.ModeType {
+		case os.ModeDir:
+			err = t.copyDir(srcPath, destPath)
+		case os.ModeSymlink, 0:
+			err = t.copyLeaf(srcPath, destPath, entry)
+		}
+		if err != nil {
+			if !t.collectError(srcPath, err) {
+				break
 			}
+			err = nil
 		}
 	}
 
 	return
 }
+
+// copyLeaf copies the file or symbolic link inside a directory, or adds it to the plan for a parallel copy.
+func (t *copyTask) copyLeaf(src, dest string, fi os.FileInfo) error {
+	target, linked := t.hardlinkTarget(fi)
+	if t.plan != nil {
+		if linked {
+			t.plan.addLink(src, dest, target)
+		} else {
+			// the copy is planned, and hard links to a failed copy are handled after the workers finish
+			t.recordHardlink(fi, dest)
+			t.plan.add(src, dest, isSymlinkFi(&fi))
+		}
+		return nil
+	}
+
+	switch {
+	case linked:
+		return t.linkFile(src, dest, target)
+	case isSymlinkFi(&fi):
+		return t.copySymlink(src, dest)
+	default:
+		err := t.copyFile(src, dest)
+		if err == nil {
+			t.recordHardlink(fi, dest)
+		}
+		return err
+	}
+}
+
+// collectError records the error of the entry and returns true if the task continues on errors, errors of the context are never collected.
+func (t *copyTask) collectError(path string, err error) bool {
+	if !t.opts.ContinueOnError || t.ctx.Err() != nil {
+		return false
+	}
+	t.errs.add(opnCopy, path, err)
+	return true
+}
+
+// finishDir applies the mode and metadata to the directory created by the copy after all entries are written, or removes it if the operation is canceled.
+func (t *copyTask) finishDir(src, dest string, mode os.FileMode, errIn error) (err error) {
+	// remove the created directory if the operation is canceled
+	if err = errIn; err != nil && t.ctx.Err() != nil {
+		_ = os.RemoveAll(dest)
+		return
+	}
+	_ = os.Chmod(dest, mode)
+	// apply metadata after all entries are written, since writing entries changes times of the directory
+	if err == nil {
+		err = t.preserveMetadata(src, dest, os.Stat)
+	}
+	return
+}
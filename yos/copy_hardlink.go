@@ -0,0 +1,67 @@
+package yos
+
+import (
+	"os"
+)
+
+// fileInode identifies a file by its device and inode numbers.
+type fileInode struct {
+	dev uint64
+	ino uint64
+}
+
+// hardlinkTarget returns the destination copied before for the same inode of the source file if hard links are preserved.
+func (t *copyTask) hardlinkTarget(fi os.FileInfo) (target string, found bool) {
+	if key, ok := t.hardlinkKey(fi); ok {
+		target, found = t.links[key]
+	}
+	return
+}
+
+// recordHardlink records the destination for the inode of the source file, so the other hard links to the inode are linked to it.
+// It should be called only after the destination is copied, otherwise the hard links would point to a missing file.
+func (t *copyTask) recordHardlink(fi os.FileInfo, dest string) {
+	key, ok := t.hardlinkKey(fi)
+	if !ok {
+		return
+	}
+	if t.links == nil {
+		t.links = make(map[fileInode]string)
+	}
+	if _, found := t.links[key]; !found {
+		t.links[key] = dest
+	}
+}
+
+// hardlinkKey returns the inode of the source file if hard links are preserved and the file has other hard links.
+func (t *copyTask) hardlinkKey(fi os.FileInfo) (key fileInode, ok bool) {
+	if !t.opts.PreserveHardlinks || !isFileFi(&fi) {
+		return
+	}
+	var st fileSysStat
+	if st, ok = getFileSysStat(fi); !ok || st.Nlink < 2 {
+		return key, false
+	}
+	return fileInode{dev: st.Dev, ino: st.Ino}, true
+}
+
+// linkFile creates a hard link at the destination to the target copied before, the existing destination will be replaced unless it's a directory.
+func (t *copyTask) linkFile(src, dest, target string) (err error) {
+	var destInfo os.FileInfo
+	if destInfo, err = os.Lstat(dest); err == nil {
+		if isDirFi(&destInfo) {
+			return opError(opnCopy, dest, errIsDirectory)
+		}
+		if err = os.Remove(dest); err != nil {
+			return opError(opnCopy, dest, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return opError(opnCopy, dest, err)
+	}
+
+	if err = os.Link(target, dest); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	t.progress.addFile(src, 0)
+	return nil
+}
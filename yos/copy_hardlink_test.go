@@ -0,0 +1,130 @@
+//go:build !windows
+// +build !windows
+
+package yos
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestCopyDirWithOptions_PreserveHardlinks(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":            "shared",
+		"source/c.txt":            "alone",
+		"source/sub/":             emptyStr,
+		"dest-exist/source/b.txt": "existing",
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+	if err := os.Link(JoinPath(srcRoot, "a.txt"), JoinPath(srcRoot, "b.txt")); err != nil {
+		t.Fatalf("failed to create hard link: %v", err)
+	}
+	if err := os.Link(JoinPath(srcRoot, "a.txt"), JoinPath(srcRoot, "sub", "d.txt")); err != nil {
+		t.Fatalf("failed to create hard link: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		dest     string
+		result   string
+		preserve bool
+		workers  int
+	}{
+		{"Preserve", "dest-seq", "dest-seq", true, 0},
+		{"Preserve with workers", "dest-par", "dest-par", true, 4},
+		{"Preserve into existing", "dest-exist", "dest-exist/source", true, 0},
+		{"Not preserve", "dest-none", "dest-none", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destRoot := JoinPath(root, tt.dest)
+			var files int64
+			opts := &CopyOptions{PreserveHardlinks: tt.preserve, Workers: tt.workers, Progress: func(p Progress) { files = p.FilesDone }}
+			if err := CopyDirWithOptions(srcRoot, destRoot, opts); err != nil {
+				t.Errorf("CopyDirWithOptions() error = %v", err)
+				return
+			}
+			destRoot = JoinPath(root, tt.result)
+			expectFileContents(t, destRoot, map[string]string{
+				"a.txt":     "shared",
+				"b.txt":     "shared",
+				"c.txt":     "alone",
+				"sub/d.txt": "shared",
+			})
+			if files != 4 {
+				t.Errorf("CopyDirWithOptions() got files = %d, want 4", files)
+			}
+
+			first, _ := os.Stat(JoinPath(destRoot, "a.txt"))
+			for _, name := range []string{"b.txt", "sub/d.txt"} {
+				fi, _ := os.Stat(JoinPath(destRoot, name))
+				if same := os.SameFile(first, fi); same != tt.preserve {
+					t.Errorf("CopyDirWithOptions() got %s linked = %v, want %v", name, same, tt.preserve)
+				}
+			}
+			other, _ := os.Stat(JoinPath(destRoot, "c.txt"))
+			if os.SameFile(first, other) {
+				t.Errorf("CopyDirWithOptions() got c.txt linked, want not")
+			}
+		})
+	}
+}
+
+func TestCopyDirWithOptions_PreserveHardlinksDirConflict(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":       "shared",
+		"dest/source/b.txt/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+	if err := os.Link(JoinPath(srcRoot, "a.txt"), JoinPath(srcRoot, "b.txt")); err != nil {
+		t.Fatalf("failed to create hard link: %v", err)
+	}
+
+	for _, workers := range []int{0, 2} {
+		t.Run(fmt.Sprintf("Workers %d", workers), func(t *testing.T) {
+			err := CopyDirWithOptions(srcRoot, JoinPath(root, "dest"), &CopyOptions{PreserveHardlinks: true, Workers: workers})
+			if err == nil {
+				t.Errorf("CopyDirWithOptions() got no error, want error")
+				return
+			}
+			expectedErrorCheck(t, err)
+		})
+	}
+}
+
+func TestCopyDirWithOptions_PreserveHardlinksFailedTarget(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":       "shared",
+		"source/sub/":        emptyStr,
+		"dest/source/a.txt/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+	for _, name := range []string{"b.txt", "sub/c.txt"} {
+		if err := os.Link(JoinPath(srcRoot, "a.txt"), JoinPath(srcRoot, name)); err != nil {
+			t.Fatalf("failed to create hard link: %v", err)
+		}
+	}
+
+	for _, workers := range []int{0, 2} {
+		t.Run(fmt.Sprintf("Workers %d", workers), func(t *testing.T) {
+			destRoot := JoinPath(root, "dest", "source")
+			defer os.Remove(JoinPath(destRoot, "b.txt"))
+			defer os.Remove(JoinPath(destRoot, "sub", "c.txt"))
+
+			// the first copy fails since a directory exists, so the other hard links are copied and linked to each other
+			err := CopyDirWithOptions(srcRoot, JoinPath(root, "dest"), &CopyOptions{PreserveHardlinks: true, Workers: workers, ContinueOnError: true})
+			if pes, ok := err.(PathErrors); !ok || len(pes) != 1 {
+				t.Errorf("CopyDirWithOptions() got error = %v, want one error for a.txt", err)
+			}
+			expectFileContents(t, destRoot, map[string]string{"b.txt": "shared", "sub/c.txt": "shared"})
+			fi1, _ := os.Stat(JoinPath(destRoot, "b.txt"))
+			if fi2, err := os.Stat(JoinPath(destRoot, "sub", "c.txt")); err != nil || !os.SameFile(fi1, fi2) {
+				t.Errorf("CopyDirWithOptions() got sub/c.txt not linked to b.txt, error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package yos
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// copyPlan holds the entries to be copied by workers, the hard links to be created and the directories to be finished afterwards for a parallel copy.
+type copyPlan struct {
+	jobs   []copyJob
+	links  []copyJob
+	dirs   []createdDir
+	failed map[string]bool
+}
+
+// copyJob represents a file or symbolic link to be copied by workers, or a hard link to the target copied before.
+type copyJob struct {
+	src    string
+	dest   string
+	target string
+	isLink bool
+}
+
+// createdDir represents a directory created by the copy, and its mode and metadata should be applied after all entries are copied.
+type createdDir struct {
+	src  string
+	dest string
+	mode os.FileMode
+}
+
+// add appends a file or symbolic link to the plan.
+func (p *copyPlan) add(src, dest string, isLink bool) {
+	p.jobs = append(p.jobs, copyJob{src: src, dest: dest, isLink: isLink})
+}
+
+// addLink appends a hard link to the plan, and it will be created after all files are copied.
+func (p *copyPlan) addLink(src, dest, target string) {
+	p.links = append(p.links, copyJob{src: src, dest: dest, target: target})
+}
+
+// copyTree copies the source directory to the destination, and uses a pool of workers if it's enabled by the options.
+//
+// For a parallel copy, directories are created and entries are planned in lexical order at first, then workers copy the entries,
+// and directories are finished from the deepest at last. The error of the first failed entry in lexical order is returned.
+func (t *copyTask) copyTree(src, dest string) (err error) {
+	if t.opts.Workers < 2 {
+		return t.copyDir(src, dest)
+	}
+
+	t.plan = &copyPlan{}
+	defer func() {
+		t.plan = nil
+	}()
+
+	if err = t.copyDir(src, dest); err == nil {
+		err = t.runJobs()
+	}
+	// the first hard link to a failed copy is copied instead, and the others are linked to it
+	replaced := make(map[string]string)
+	for _, link := range t.plan.links {
+		if err != nil {
+			break
+		}
+		target, found := link.target, true
+		if t.plan.failed[target] {
+			target, found = replaced[link.target]
+		}
+		if found {
+			err = t.linkFile(link.src, link.dest, target)
+		} else if err = t.copyFile(link.src, link.dest); err == nil {
+			replaced[link.target] = link.dest
+		}
+		if err != nil && t.collectError(link.src, err) {
+			err = nil
+		}
+	}
+
+	dirs := t.plan.dirs
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		err = t.finishDir(dir.src, dir.dest, dir.mode, err)
+	}
+	return
+}
+
+// runJobs copies all planned entries with workers, and stops dispatching entries if any error occurs or the context is done.
+func (t *copyTask) runJobs() (err error) {
+	var (
+		jobs    = t.plan.jobs
+		errs    = make([]error, len(jobs))
+		queue   = make(chan int)
+		workers = t.opts.Workers
+		failed  int32
+		wg      sync.WaitGroup
+	)
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range queue {
+				job := jobs[idx]
+				if job.isLink {
+					errs[idx] = t.copySymlink(job.src, job.dest)
+				} else {
+					errs[idx] = t.copyFile(job.src, job.dest)
+				}
+				if errs[idx] != nil && !t.opts.ContinueOnError {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+	for ; dispatched < len(jobs); dispatched++ {
+		if atomic.LoadInt32(&failed) != 0 || t.ctx.Err() != nil {
+			break
+		}
+		queue <- dispatched
+	}
+	close(queue)
+	wg.Wait()
+
+	// return the first error or collect errors in lexical order for determinism
+	t.plan.failed = make(map[string]bool)
+	for idx, e := range errs {
+		if e == nil {
+			continue
+		}
+		if !t.collectError(jobs[idx].src, e) {
+			return e
+		}
+		t.plan.failed[jobs[idx].dest] = true
+	}
+	if dispatched < len(jobs) {
+		err = contextError(t.ctx, opnCopy, jobs[dispatched].src)
+	}
+	return
+}
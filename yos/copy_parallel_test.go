@@ -0,0 +1,141 @@
+package yos
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCopyDirWithOptions_Workers(t *testing.T) {
+	entries := map[string]string{
+		"source/empty/": emptyStr,
+		"source/link":   "->d0/f0.txt",
+	}
+	for d := 0; d < 5; d++ {
+		for f := 0; f < 20; f++ {
+			entries[fmt.Sprintf("source/d%d/f%d.txt", d, f)] = strings.Repeat(fmt.Sprint(d, f), f*100)
+		}
+		entries[fmt.Sprintf("source/d%d/nested/link.txt", d)] = "->../f1.txt"
+	}
+	root := makeTestTree(t, entries)
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+
+	// change times of directories to check if they're applied after all entries are written
+	oldTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	dirs, _ := ListDir(srcRoot)
+	for _, dir := range dirs {
+		_ = os.Chtimes(dir.Path, oldTime, oldTime)
+	}
+
+	for _, workers := range []int{0, 1, 2, 8, 1000} {
+		t.Run(fmt.Sprintf("Workers %d", workers), func(t *testing.T) {
+			destRoot := JoinPath(root, fmt.Sprintf("dest-%d", workers))
+			var (
+				mu    sync.Mutex
+				files int64
+			)
+			// the progress may be reported concurrently and out of order by workers
+			opts := &CopyOptions{Workers: workers, PreserveTimes: true, Progress: func(p Progress) {
+				mu.Lock()
+				defer mu.Unlock()
+				if p.FilesDone > files {
+					files = p.FilesDone
+				}
+			}}
+			if err := CopyDirWithOptions(srcRoot, destRoot, opts); err != nil {
+				t.Errorf("CopyDirWithOptions() error = %v", err)
+				return
+			}
+			if same, err := SameDirEntries(srcRoot, destRoot); err != nil || !same {
+				t.Errorf("CopyDirWithOptions() the directories are not the same: %v, %v, error: %v", srcRoot, destRoot, err)
+			}
+			if files != 106 {
+				t.Errorf("CopyDirWithOptions() got files done = %d, want %d", files, 106)
+			}
+			destDirs, _ := ListDir(destRoot)
+			for _, dir := range destDirs {
+				if !dir.Info.ModTime().Equal(oldTime) {
+					t.Errorf("CopyDirWithOptions() got mtime = %v for %v, want %v", dir.Info.ModTime(), dir.Path, oldTime)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyDirWithOptions_WorkersError(t *testing.T) {
+	entries := map[string]string{}
+	for f := 0; f < 30; f++ {
+		entries[fmt.Sprintf("source/f%02d.txt", f)] = strings.Repeat("x", f*1000)
+	}
+	// directories with the names of files can't be overwritten
+	entries["out/source/f07.txt/"] = emptyStr
+	entries["out/source/f21.txt/"] = emptyStr
+	root := makeTestTree(t, entries)
+	defer os.RemoveAll(root)
+
+	for i := 0; i < 10; i++ {
+		err := CopyDirWithOptions(JoinPath(root, "source"), JoinPath(root, "out"), &CopyOptions{Workers: 4})
+		if err == nil {
+			t.Fatalf("CopyDirWithOptions() got no error")
+		}
+		expectedErrorCheck(t, err)
+		if pe, ok := err.(*os.PathError); !ok || !strings.HasSuffix(pe.Path, "f07.txt") {
+			t.Fatalf("CopyDirWithOptions() got error = %v, want error for f07.txt", err)
+		}
+	}
+}
+
+func TestCopyDirWithOptions_WorkersRename(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":         "new a",
+		"source/a (1).txt":     "new a1",
+		"out/source/a.txt":     "old a",
+		"out/source/other.txt": "other",
+	})
+	defer os.RemoveAll(root)
+
+	opts := &CopyOptions{Workers: 4, Conflict: ConflictRename}
+	if err := CopyDirWithOptions(JoinPath(root, "source"), JoinPath(root, "out"), opts); err != nil {
+		t.Fatalf("CopyDirWithOptions() error = %v", err)
+	}
+	expectFileContents(t, root, map[string]string{
+		"out/source (1)/a.txt":     "new a",
+		"out/source (1)/a (1).txt": "new a1",
+		"out/source/a.txt":         "old a",
+	})
+}
+
+func TestCopyDirWithOptions_WorkersSlowProgress(t *testing.T) {
+	entries := make(map[string]string)
+	for f := 0; f < 16; f++ {
+		entries[fmt.Sprintf("source/f%d.txt", f)] = strings.Repeat("x", f)
+	}
+	root := makeTestTree(t, entries)
+	defer os.RemoveAll(root)
+
+	// a slow progress function shouldn't serialize the workers
+	var running, maxRunning int32
+	opts := &CopyOptions{Workers: 4, Progress: func(p Progress) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}}
+	if err := CopyDirWithOptions(JoinPath(root, "source"), JoinPath(root, "dest"), opts); err != nil {
+		t.Errorf("CopyDirWithOptions() error = %v", err)
+		return
+	}
+	if maxRunning < 2 {
+		t.Errorf("CopyDirWithOptions() got progress called concurrently by %d workers at most, want more", maxRunning)
+	}
+}
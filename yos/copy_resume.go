@@ -0,0 +1,81 @@
+package yos
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+	"os"
+)
+
+// resumeBlockSize is the size of the tail block compared to check the partial destination before resuming.
+const resumeBlockSize = 1024 * 1024
+
+// resumeCopyFile copies content of the source file to the destination file from the end of the verified prefix of the destination.
+// The partial destination is kept on errors for resuming later, unless its content doesn't match the source.
+func (t *copyTask) resumeCopyFile(srcFile *os.File, src, dest string, srcInfo os.FileInfo, bufferSize int64, sum hash.Hash) (err error) {
+	var destFile *os.File
+	if destFile, err = os.OpenFile(dest, os.O_RDWR|os.O_CREATE, srcInfo.Mode()); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	defer func() {
+		if fe := destFile.Close(); fe != nil && err == nil {
+			err = opError(opnCopy, dest, fe)
+		}
+		// remove the bad copy only, the others can be resumed
+		if errors.Is(err, ErrChecksumMismatch) {
+			_ = os.Remove(dest)
+		}
+	}()
+
+	var offset int64
+	if offset, err = resumeOffset(srcFile, destFile, src, dest, srcInfo.Size()); err != nil {
+		return
+	}
+	if err = destFile.Truncate(offset); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	if sum != nil {
+		if err = hashFileContent(sum, srcFile, src, offset); err != nil {
+			return
+		}
+	}
+	if _, err = srcFile.Seek(offset, io.SeekStart); err != nil {
+		return opError(opnCopy, src, err)
+	}
+	if _, err = destFile.Seek(offset, io.SeekStart); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	t.progress.addBytes(src, offset)
+
+	if err = t.bufferCopyContent(srcFile, destFile, src, dest, bufferSize, sum); err == nil && sum != nil {
+		err = verifyCopiedFile(destFile, dest, sum)
+	}
+	return
+}
+
+// resumeOffset returns the offset to resume copying from, which is the size of the destination if its tail block matches the source,
+// otherwise it's zero since the rest of the prefix can't be trusted either. It's also zero if the destination is larger than the source.
+func resumeOffset(srcFile, destFile *os.File, src, dest string, size int64) (offset int64, err error) {
+	var destInfo os.FileInfo
+	if destInfo, err = destFile.Stat(); err != nil {
+		return 0, opError(opnCopy, dest, err)
+	}
+	if offset = destInfo.Size(); offset > size || offset == 0 {
+		return 0, nil
+	}
+
+	start := (offset - 1) / resumeBlockSize * resumeBlockSize
+	length := int(offset - start)
+	srcBuf, destBuf := make([]byte, length), make([]byte, length)
+	if _, err = srcFile.ReadAt(srcBuf, start); err != nil {
+		return 0, opError(opnCopy, src, err)
+	}
+	if _, err = destFile.ReadAt(destBuf, start); err != nil {
+		return 0, opError(opnCopy, dest, err)
+	}
+	if !bytes.Equal(srcBuf, destBuf) {
+		offset = 0
+	}
+	return
+}
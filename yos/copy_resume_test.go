@@ -0,0 +1,97 @@
+package yos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCopyFileWithOptions_Resume(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"dir/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	// the source spans several blocks with a partial block at the end
+	data := make([]byte, 5*resumeBlockSize/2)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	src := JoinPath(root, "source.bin")
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	corrupt := func(b []byte, offsets ...int) []byte {
+		b = append([]byte(nil), b...)
+		for _, off := range offsets {
+			b[off] ^= 0xff
+		}
+		return b
+	}
+	partial := data[:3*resumeBlockSize/2]
+
+	tests := []struct {
+		name    string
+		dest    []byte
+		opts    *CopyOptions
+		want    []byte
+		wantOff int64
+	}{
+		{"No destination", nil, &CopyOptions{Resume: true}, data, 0},
+		{"Empty destination", []byte{}, &CopyOptions{Resume: true}, data, 0},
+		{"Partial destination", partial, &CopyOptions{Resume: true}, data, int64(len(partial))},
+		{"Bad tail block", corrupt(partial, len(partial)-1), &CopyOptions{Resume: true}, data, 0},
+		{"Block matches by chance", corrupt(data[:9*resumeBlockSize/4], 0, 9*resumeBlockSize/4-1), &CopyOptions{Resume: true}, data, 0},
+		{"Bad blocks", corrupt(partial, 10, len(partial)-1), &CopyOptions{Resume: true}, data, 0},
+		{"Complete destination", data, &CopyOptions{Resume: true}, data, int64(len(data))},
+		{"Larger destination", append(append([]byte(nil), data...), 'x'), &CopyOptions{Resume: true}, data, 0},
+		{"Partial destination with verify", partial, &CopyOptions{Resume: true, Verify: true}, data, int64(len(partial))},
+		{"Prefix mismatch with verify", corrupt(partial, 0), &CopyOptions{Resume: true, Verify: true}, nil, int64(len(partial))},
+		{"Not resumed without option", corrupt(partial, 0), nil, data, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := JoinPath(root, "dir", "dest.bin")
+			_ = os.Remove(dest)
+			if tt.dest != nil {
+				if err := ioutil.WriteFile(dest, tt.dest, 0644); err != nil {
+					t.Fatalf("failed to write destination: %v", err)
+				}
+			}
+
+			var first *Progress
+			opts := &CopyOptions{}
+			if tt.opts != nil {
+				opts = tt.opts
+			}
+			opts.Progress = func(p Progress) {
+				if first == nil {
+					first = &p
+				}
+			}
+			err := CopyFileWithOptions(src, dest, opts)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("CopyFileWithOptions() got no error, want checksum mismatch")
+				} else {
+					expectedErrorCheck(t, err)
+				}
+				if Exist(dest) {
+					t.Errorf("CopyFileWithOptions() got bad copy left")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			if got, err := ioutil.ReadFile(dest); err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("CopyFileWithOptions() got different content, error = %v", err)
+			}
+			if tt.opts != nil && (first == nil || first.BytesDone < tt.wantOff) {
+				t.Errorf("CopyFileWithOptions() got first progress = %+v, want resumed from %d", first, tt.wantOff)
+			}
+		})
+	}
+}
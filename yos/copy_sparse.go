@@ -0,0 +1,142 @@
+package yos
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"syscall"
+)
+
+// sparseBlockSize is the size of blocks to check for zeros while copying sparse files.
+const sparseBlockSize = 4096
+
+// zeroBlock is a block filled with zeros to compare with.
+var zeroBlock = make([]byte, sparseBlockSize)
+
+// sparseCopyContent copies content from the source file to the destination file, and skips holes and blocks of zeros to keep the destination sparse.
+//
+// Holes are located by SEEK_DATA and SEEK_HOLE if they're supported, otherwise every block is read and checked for zeros.
+func (t *copyTask) sparseCopyContent(srcFile, destFile *os.File, src, dest string, size, bufferSize int64, sum hash.Hash) (err error) {
+	if bufferSize < sparseBlockSize {
+		bufferSize = sparseBlockSize
+	}
+	buf := make([]byte, bufferSize)
+
+	var (
+		offset, dataStart, dataEnd int64
+		truncated                  bool
+	)
+	for offset < size {
+		if dataStart, dataEnd, err = nextDataRange(srcFile, offset, size); err != nil {
+			// fall back to check every block for the rest of the file
+			dataStart, dataEnd, err = offset, size, nil
+		}
+
+		// skip the hole, and count it as done
+		if dataStart > offset {
+			t.progress.addBytes(src, dataStart-offset)
+			hashZeros(sum, dataStart-offset)
+		}
+		if offset = dataEnd; dataStart < dataEnd {
+			if offset, err = t.copySparseRange(srcFile, destFile, src, dest, dataStart, dataEnd, buf, sum); err != nil {
+				return
+			} else if offset < dataEnd {
+				// the source is truncated while copying
+				truncated = true
+				break
+			}
+		}
+	}
+
+	// keep reading until the end, since the source may grow while copying or report zero size like files in procfs
+	if !truncated {
+		if offset, err = t.copySparseRange(srcFile, destFile, src, dest, offset, math.MaxInt64, buf, sum); err != nil {
+			return
+		}
+	}
+
+	// extend the destination to the size for the trailing hole
+	if err = destFile.Truncate(offset); err != nil {
+		err = opError(opnCopy, dest, err)
+	}
+	return
+}
+
+// copySparseRange copies the range of content from the source file to the destination file, and skips blocks of zeros.
+// It returns the end offset actually copied, which is less than the given end if the source is truncated.
+func (t *copyTask) copySparseRange(srcFile, destFile *os.File, src, dest string, start, end int64, buf []byte, sum hash.Hash) (offset int64, err error) {
+	for offset = start; offset < end; {
+		if err = contextError(t.ctx, opnCopy, src); err != nil {
+			return
+		}
+
+		chunk := buf
+		if remain := end - offset; remain < int64(len(chunk)) {
+			chunk = chunk[:remain]
+		}
+		var nr int
+		if nr, err = srcFile.ReadAt(chunk, offset); err == io.EOF {
+			end, err = offset+int64(nr), nil
+		} else if err != nil {
+			err = opError(opnCopy, src, err)
+			return
+		}
+		if sum != nil {
+			sum.Write(chunk[:nr])
+		}
+		if err = writeNonZeroBlocks(destFile, chunk[:nr], offset); err != nil {
+			err = opError(opnCopy, dest, err)
+			return
+		}
+		offset += int64(nr)
+		t.progress.addBytes(src, int64(nr))
+		if nr == 0 {
+			break
+		}
+	}
+	return
+}
+
+// writeNonZeroBlocks writes the data to the file at the offset, and skips the blocks filled with zeros.
+func writeNonZeroBlocks(file *os.File, data []byte, offset int64) (err error) {
+	runStart := -1
+	for pos := 0; pos < len(data); pos += sparseBlockSize {
+		blockEnd := pos + sparseBlockSize
+		if blockEnd > len(data) {
+			blockEnd = len(data)
+		}
+
+		if isZero := bytes.Equal(data[pos:blockEnd], zeroBlock[:blockEnd-pos]); !isZero && runStart < 0 {
+			runStart = pos
+		} else if isZero && runStart >= 0 {
+			if _, err = file.WriteAt(data[runStart:pos], offset+int64(runStart)); err != nil {
+				return
+			}
+			runStart = -1
+		}
+	}
+	if runStart >= 0 {
+		_, err = file.WriteAt(data[runStart:], offset+int64(runStart))
+	}
+	return
+}
+
+// nextDataRange returns the range of the next data region starting from the offset by SEEK_DATA and SEEK_HOLE.
+func nextDataRange(file *os.File, offset, size int64) (start, end int64, err error) {
+	if start, err = file.Seek(offset, seekData); err != nil {
+		// no more data after the offset
+		if underlyingError(err) == syscall.ENXIO {
+			return size, size, nil
+		}
+		return
+	}
+	if end, err = file.Seek(start, seekHole); err != nil {
+		return
+	}
+	if end > size {
+		end = size
+	}
+	return
+}
@@ -0,0 +1,7 @@
+package yos
+
+// whence values for seeking data and holes of sparse files on macOS.
+const (
+	seekHole = 3
+	seekData = 4
+)
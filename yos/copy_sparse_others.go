@@ -0,0 +1,9 @@
+// +build !darwin
+
+package yos
+
+// whence values for seeking data and holes of sparse files, it's unsupported on Windows and will fall back to checking zeros.
+const (
+	seekData = 3
+	seekHole = 4
+)
@@ -0,0 +1,92 @@
+package yos
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCopyFileWithOptions_Sparse(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"dense.txt": strings.Repeat("dense", 10000),
+		"empty.txt": emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	// create sparse files with holes at the beginning, in the middle and at the end
+	const mb = 1024 * 1024
+	sparseFiles := map[string][]int64{
+		"sparse-middle.img":   {0, 4 * mb, 8*mb - 10},
+		"sparse-leading.img":  {3*mb + 100},
+		"sparse-trailing.img": {0, -8 * mb},
+		"sparse-zeros.img":    {},
+	}
+	for name, offsets := range sparseFiles {
+		file, err := os.Create(JoinPath(root, name))
+		if err != nil {
+			t.Fatalf("fail to create sparse file: %v", err)
+		}
+		for _, off := range offsets {
+			if off < 0 {
+				err = file.Truncate(-off)
+			} else {
+				_, err = file.WriteAt([]byte("data block"), off)
+			}
+			if err != nil {
+				t.Fatalf("fail to write sparse file: %v", err)
+			}
+		}
+		if name == "sparse-zeros.img" {
+			_, err = file.Write(make([]byte, 2*mb))
+		}
+		_ = file.Close()
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		strategy CopyStrategy
+		sparse   bool
+	}{
+		{"Dense file", "dense.txt", CopyAuto, true},
+		{"Empty file", "empty.txt", CopyAuto, true},
+		{"Holes in the middle", "sparse-middle.img", CopyBuffer, true},
+		{"Leading hole", "sparse-leading.img", CopyBuffer, true},
+		{"Trailing hole", "sparse-trailing.img", CopyBuffer, true},
+		{"Written zeros", "sparse-zeros.img", CopyBuffer, true},
+		{"Holes with auto strategy", "sparse-middle.img", CopyAuto, true},
+		{"Holes without sparse", "sparse-middle.img", CopyBuffer, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcPath, destPath := JoinPath(root, tt.src), JoinPath(root, "out-"+tt.src)
+			defer os.Remove(destPath)
+
+			var copied int64
+			opts := &CopyOptions{Strategy: tt.strategy, Sparse: tt.sparse, Progress: func(p Progress) { copied = p.BytesDone }}
+			if err := CopyFileWithOptions(srcPath, destPath, opts); err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			if same, err := SameFileContent(srcPath, destPath); err != nil || !same {
+				t.Errorf("CopyFileWithOptions() the files are not the same: %v, %v, error: %v", srcPath, destPath, err)
+				return
+			}
+			size, _ := GetFileSize(srcPath)
+			if copied != size {
+				t.Errorf("CopyFileWithOptions() got progress bytes = %d, want %d", copied, size)
+			}
+
+			// check allocated blocks if the platform supports
+			srcInfo, _ := os.Stat(srcPath)
+			destInfo, _ := os.Stat(destPath)
+			srcSt, ok1 := getFileSysStat(srcInfo)
+			destSt, ok2 := getFileSysStat(destInfo)
+			if ok1 && ok2 && strings.HasPrefix(tt.src, "sparse") && srcSt.Blocks*512 < size {
+				if gotSparse := destSt.Blocks*512 < size; gotSparse != tt.sparse {
+					t.Errorf("CopyFileWithOptions() got sparse = %v (%d blocks for %d bytes), want %v", gotSparse, destSt.Blocks, size, tt.sparse)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -340,3 +341,153 @@ func BenchmarkCopySymlink(b *testing.B) {
 		_ = CopySymlink(inputPath, outputPath)
 	}
 }
+
+func TestCopyFileWithOptions(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source.txt": "Hello, World!",
+	})
+	defer os.RemoveAll(root)
+
+	srcPath := JoinPath(root, "source.txt")
+	oldTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(srcPath, oldTime, oldTime); err != nil {
+		t.Fatalf("CopyFileWithOptions() fail to change times of %v: %v", srcPath, err)
+	}
+
+	tests := []struct {
+		name      string
+		destName  string
+		opts      *CopyOptions
+		wantMtime bool
+	}{
+		{"Nil options", "nil.txt", nil, false},
+		{"Default options", "default.txt", &CopyOptions{}, false},
+		{"Preserve times", "times.txt", &CopyOptions{PreserveTimes: true}, true},
+		{"Preserve all", "all.txt", &CopyOptions{PreserveTimes: true, PreserveOwner: true, PreserveXattrs: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := JoinPath(root, tt.destName)
+			if err := CopyFileWithOptions(srcPath, destPath, tt.opts); err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			if same, err := SameFileContent(srcPath, destPath); err != nil || !same {
+				t.Errorf("CopyFileWithOptions() the files are not the same: %v, %v, error: %v", srcPath, destPath, err)
+				return
+			}
+
+			fi, err := os.Stat(destPath)
+			if err != nil {
+				t.Errorf("CopyFileWithOptions() fail to stat %v: %v", destPath, err)
+				return
+			}
+			if gotMtime := fi.ModTime().Equal(oldTime); gotMtime != tt.wantMtime {
+				t.Errorf("CopyFileWithOptions() got mtime = %v, want preserved = %v", fi.ModTime(), tt.wantMtime)
+			}
+		})
+	}
+}
+
+func TestCopyDirWithOptions(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/text.txt":         "text",
+		"source/empty-dir/":       emptyStr,
+		"source/nested/file.txt":  "nested",
+		"source/nested/link.txt":  "->file.txt",
+		"source/nested/deep/a.md": "# A",
+	})
+	defer os.RemoveAll(root)
+
+	srcRoot, destRoot := JoinPath(root, "source"), JoinPath(root, "destination")
+	entries, err := ListAll(srcRoot)
+	if err != nil {
+		t.Fatalf("CopyDirWithOptions() fail to list %v: %v", srcRoot, err)
+	}
+
+	// change times of nested entries first, and the root at last
+	oldTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if err = setEntryTimes(entry.Path, oldTime, oldTime, isSymlinkFi(&entry.Info)); err != nil {
+			t.Fatalf("CopyDirWithOptions() fail to change times of %v: %v", entry.Path, err)
+		}
+	}
+	if err = os.Chtimes(srcRoot, oldTime, oldTime); err != nil {
+		t.Fatalf("CopyDirWithOptions() fail to change times of %v: %v", srcRoot, err)
+	}
+
+	if err = CopyDirWithOptions(srcRoot, destRoot, &CopyOptions{PreserveTimes: true, PreserveOwner: true}); err != nil {
+		t.Fatalf("CopyDirWithOptions() error = %v", err)
+	}
+	if same, err := SameDirEntries(srcRoot, destRoot); err != nil || !same {
+		t.Fatalf("CopyDirWithOptions() the directories are not the same: %v, %v, error: %v", srcRoot, destRoot, err)
+	}
+
+	destEntries, err := ListAll(destRoot)
+	if err != nil {
+		t.Fatalf("CopyDirWithOptions() fail to list %v: %v", destRoot, err)
+	}
+	if rootInfo, err := os.Stat(destRoot); err != nil || !rootInfo.ModTime().Equal(oldTime) {
+		t.Errorf("CopyDirWithOptions() got unexpected mtime for %v, error: %v", destRoot, err)
+	}
+	for _, entry := range destEntries {
+		if isSymlinkFi(&entry.Info) && !IsOnLinux() {
+			continue
+		}
+		if mtime := entry.Info.ModTime(); !mtime.Equal(oldTime) {
+			t.Errorf("CopyDirWithOptions() got mtime = %v for %v, want %v", mtime, entry.Path, oldTime)
+		}
+	}
+}
+
+func TestCopyFileWithOptions_Strategy(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"empty.txt": emptyStr,
+		"small.txt": "Hello, World!",
+		"large.txt": strings.Repeat("0123456789abcdef", 2*defaultBufferSize/16+7),
+	})
+	defer os.RemoveAll(root)
+
+	strategies := []struct {
+		name     string
+		strategy CopyStrategy
+		kernel   bool
+	}{
+		{"Auto", CopyAuto, false},
+		{"Buffer", CopyBuffer, false},
+		{"Reflink", CopyReflink, true},
+		{"FileRange", CopyFileRange, true},
+		{"Sendfile", CopySendfile, true},
+	}
+	for _, st := range strategies {
+		for _, name := range []string{"empty.txt", "small.txt", "large.txt"} {
+			t.Run(st.name+" "+name, func(t *testing.T) {
+				srcPath, destPath := JoinPath(root, name), JoinPath(root, st.name+"-"+name)
+				var copied int64
+				opts := &CopyOptions{Strategy: st.strategy, Progress: func(p Progress) { copied = p.BytesDone }}
+				err := CopyFileWithOptions(srcPath, destPath, opts)
+				if st.kernel && (!IsOnLinux() || err != nil) {
+					// kernel strategies may be unsupported by the platform or file systems
+					if pe, ok := err.(*os.PathError); !ok || pe.Err != errUnsupportedStrategy {
+						t.Errorf("CopyFileWithOptions() got error = %v, want unsupported strategy", err)
+					}
+					if Exist(destPath) {
+						t.Errorf("CopyFileWithOptions() fail to clean up: %v", destPath)
+					}
+					return
+				}
+				if err != nil {
+					t.Errorf("CopyFileWithOptions() error = %v", err)
+					return
+				}
+				if same, err := SameFileContent(srcPath, destPath); err != nil || !same {
+					t.Errorf("CopyFileWithOptions() the files are not the same: %v, %v, error: %v", srcPath, destPath, err)
+				}
+				if size, _ := GetFileSize(srcPath); copied != size {
+					t.Errorf("CopyFileWithOptions() got progress bytes = %d, want %d", copied, size)
+				}
+			})
+		}
+	}
+}
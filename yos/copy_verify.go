@@ -0,0 +1,62 @@
+package yos
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/1set/gut/yhash"
+)
+
+// ErrChecksumMismatch indicates the content of the copied file differs from the source, it's wrapped in *os.PathError for copy operations with Verify.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// verifyCopiedFile flushes the destination file, and compares the digest of its content re-read from the disk with the digest of the source.
+func verifyCopiedFile(destFile *os.File, dest string, sum hash.Hash) (err error) {
+	if err = destFile.Sync(); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+
+	var digest string
+	if digest, err = yhash.FileSHA256(destFile.Name()); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	if digest != hex.EncodeToString(sum.Sum(nil)) {
+		return opError(opnCopy, dest, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// hashCopiedContent writes the content of the source file as many bytes as written to the destination file to the hash,
+// it's used when the content is copied by the kernel without being read.
+func hashCopiedContent(sum hash.Hash, srcFile, destFile *os.File, src, dest string) (err error) {
+	var written int64
+	if written, err = destFile.Seek(0, io.SeekCurrent); err != nil {
+		return opError(opnCopy, dest, err)
+	}
+	return hashFileContent(sum, srcFile, src, written)
+}
+
+// hashFileContent writes the content of the file to the hash, it's used for the content copied without being read.
+func hashFileContent(sum hash.Hash, file *os.File, path string, size int64) (err error) {
+	if _, err = io.Copy(sum, io.NewSectionReader(file, 0, size)); err != nil {
+		err = opError(opnCopy, path, err)
+	}
+	return
+}
+
+// hashZeros writes zeros of the given length to the hash if it's not nil, it's used for holes skipped in sparse files.
+func hashZeros(sum hash.Hash, n int64) {
+	if sum == nil {
+		return
+	}
+	for ; n > 0; n -= sparseBlockSize {
+		if n < sparseBlockSize {
+			sum.Write(zeroBlock[:n])
+			break
+		}
+		sum.Write(zeroBlock)
+	}
+}
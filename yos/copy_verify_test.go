@@ -0,0 +1,112 @@
+package yos
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCopyFileWithOptions_Verify(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"dense.txt": strings.Repeat("dense", 100000),
+		"empty.txt": emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	// create a sparse file with holes at the beginning and the end
+	file, err := os.Create(JoinPath(root, "sparse.img"))
+	if err != nil {
+		t.Fatalf("fail to create sparse file: %v", err)
+	}
+	if _, err = file.WriteAt([]byte("data block"), 3*1024*1024+100); err == nil {
+		err = file.Truncate(8 * 1024 * 1024)
+	}
+	_ = file.Close()
+	if err != nil {
+		t.Fatalf("fail to write sparse file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		opts *CopyOptions
+	}{
+		{"Auto strategy", "dense.txt", &CopyOptions{Verify: true}},
+		{"Buffer strategy", "dense.txt", &CopyOptions{Verify: true, Strategy: CopyBuffer}},
+		{"Empty file", "empty.txt", &CopyOptions{Verify: true}},
+		{"Sparse file", "sparse.img", &CopyOptions{Verify: true, Sparse: true, Strategy: CopyBuffer}},
+		{"Atomic", "dense.txt", &CopyOptions{Verify: true, Atomic: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dest := JoinPath(root, tt.src), JoinPath(root, "copy-"+tt.src)
+			if err := CopyFileWithOptions(src, dest, tt.opts); err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			if same, err := SameFileContent(src, dest); err != nil || !same {
+				t.Errorf("CopyFileWithOptions() got different content, error = %v", err)
+			}
+		})
+	}
+
+	if err = CopyDirWithOptions(root, root+"-copy", &CopyOptions{Verify: true, Workers: 4}); err != nil {
+		t.Errorf("CopyDirWithOptions() error = %v", err)
+	}
+	defer os.RemoveAll(root + "-copy")
+	if same, err := SameDirEntries(root, root+"-copy"); err != nil || !same {
+		t.Errorf("CopyDirWithOptions() got different entries, error = %v", err)
+	}
+}
+
+func Test_verifyCopiedFile(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"file.txt": "content",
+	})
+	defer os.RemoveAll(root)
+	path := JoinPath(root, "file.txt")
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("fail to open file: %v", err)
+	}
+	defer file.Close()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"Same content", "content", false},
+		{"Different content", "CONTENT", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := sha256.New()
+			sum.Write([]byte(tt.content))
+			err := verifyCopiedFile(file, path, sum)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyCopiedFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				expectedErrorCheck(t, err)
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Errorf("verifyCopiedFile() got error = %v, want ErrChecksumMismatch", err)
+				}
+			}
+		})
+	}
+}
+
+func Test_hashZeros(t *testing.T) {
+	for _, n := range []int64{0, 1, sparseBlockSize - 1, sparseBlockSize, 3*sparseBlockSize + 7} {
+		got, want := sha256.New(), sha256.New()
+		hashZeros(got, n)
+		want.Write(make([]byte, n))
+		if string(got.Sum(nil)) != string(want.Sum(nil)) {
+			t.Errorf("hashZeros(%d) got different digest", n)
+		}
+	}
+	hashZeros(nil, 10)
+}
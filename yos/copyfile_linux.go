@@ -0,0 +1,97 @@
+package yos
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// maxKernelCopyChunk is the maximum number of bytes to copy in a system call, so that the context and progress are checked in time.
+const maxKernelCopyChunk = 16 * 1024 * 1024
+
+// kernelCopyContent copies content from the source file to the destination file with the kernel strategy,
+// and returns false without errors if the strategy is not supported for the files and nothing is written.
+func (t *copyTask) kernelCopyContent(strategy CopyStrategy, srcFile, destFile *os.File, src, dest string, size int64) (handled bool, err error) {
+	srcFd, destFd := int(srcFile.Fd()), int(destFile.Fd())
+	switch strategy {
+	case CopyReflink:
+		if ioctlFiclone == 0 {
+			return
+		}
+		if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, uintptr(destFd), ioctlFiclone, uintptr(srcFd)); e != 0 {
+			if !isKernelCopyUnsupported(e) {
+				handled, err = true, opError(opnCopy, dest, e)
+			}
+			return
+		}
+		// the content is cloned at once, and the offset of the destination is unchanged, so move it to the end of the cloned content
+		var cloned int64
+		if cloned, err = destFile.Seek(0, io.SeekEnd); err != nil {
+			return true, opError(opnCopy, dest, err)
+		}
+		t.progress.addBytes(src, cloned)
+		return true, nil
+	case CopyFileRange:
+		if sysCopyFileRange == 0 {
+			return
+		}
+		return t.kernelCopyChunks(srcFile, src, dest, func(chunk int) (int, error) {
+			n, _, e := syscall.Syscall6(sysCopyFileRange, uintptr(srcFd), 0, uintptr(destFd), 0, uintptr(chunk), 0)
+			if e != 0 {
+				return 0, e
+			}
+			return int(n), nil
+		})
+	case CopySendfile:
+		return t.kernelCopyChunks(srcFile, src, dest, func(chunk int) (int, error) {
+			return syscall.Sendfile(destFd, srcFd, nil, chunk)
+		})
+	}
+	return
+}
+
+// kernelCopyChunks copies content with the system call in chunks until the end of the source, and checks the context and reports the progress between chunks.
+// The size is not trusted to stop copying, since the source may grow while copying or report a wrong size like files in procfs and sysfs.
+func (t *copyTask) kernelCopyChunks(srcFile *os.File, src, dest string, copyChunk func(chunk int) (int, error)) (handled bool, err error) {
+	var written int64
+	for {
+		if err = contextError(t.ctx, opnCopy, src); err != nil {
+			return true, err
+		}
+
+		n, e := copyChunk(maxKernelCopyChunk)
+		if e != nil {
+			// fall back to other strategies only if nothing is written yet
+			if written == 0 && isKernelCopyUnsupported(e) {
+				return false, nil
+			}
+			return true, opError(opnCopy, dest, e)
+		}
+		if n == 0 {
+			// some file systems like procfs and sysfs can't be copied by the kernel whatever the size is,
+			// so fall back if nothing is written yet, unless the source is really empty
+			if written == 0 && !isFileEmpty(srcFile) {
+				return false, nil
+			}
+			return true, nil
+		}
+		written += int64(n)
+		t.progress.addBytes(src, int64(n))
+	}
+}
+
+// isFileEmpty indicates whether there is nothing to read from the start of the file.
+func isFileEmpty(file *os.File) bool {
+	var buf [1]byte
+	n, err := file.ReadAt(buf[:], 0)
+	return n == 0 && err == io.EOF
+}
+
+// isKernelCopyUnsupported indicates whether the error means the kernel strategy is not supported for the files.
+func isKernelCopyUnsupported(err error) bool {
+	switch err {
+	case syscall.ENOSYS, syscall.EXDEV, syscall.EINVAL, syscall.EOPNOTSUPP, syscall.ENOTTY, syscall.EBADF, syscall.EPERM:
+		return true
+	}
+	return false
+}
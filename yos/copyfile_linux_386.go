@@ -0,0 +1,7 @@
+package yos
+
+// system call numbers and requests for kernel strategies to copy files on linux/386.
+const (
+	sysCopyFileRange uintptr = 377
+	ioctlFiclone     uintptr = 0x40049409
+)
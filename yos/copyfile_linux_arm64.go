@@ -0,0 +1,7 @@
+package yos
+
+// system call numbers and requests for kernel strategies to copy files on linux/arm64.
+const (
+	sysCopyFileRange uintptr = 285
+	ioctlFiclone     uintptr = 0x40049409
+)
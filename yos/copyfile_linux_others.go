@@ -0,0 +1,9 @@
+// +build linux,!amd64,!arm64,!386,!arm
+
+package yos
+
+// kernel strategies of CopyReflink and CopyFileRange are not supported on this architecture yet.
+const (
+	sysCopyFileRange uintptr = 0
+	ioctlFiclone     uintptr = 0
+)
@@ -0,0 +1,79 @@
+package yos
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCopyFileWithOptions_ZeroStatSize(t *testing.T) {
+	const src = "/proc/self/cmdline"
+	if fi, err := os.Stat(src); err != nil || fi.Size() != 0 {
+		t.Skipf("Skipping for unexpected procfs file: %v", err)
+	}
+	root := makeTestTree(t, map[string]string{
+		"dir/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name string
+		opts *CopyOptions
+	}{
+		{"Auto strategy", &CopyOptions{}},
+		{"Buffer strategy", &CopyOptions{Strategy: CopyBuffer}},
+		{"Sparse", &CopyOptions{Sparse: true}},
+		{"Verify", &CopyOptions{Verify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the copy is read-only like the source, so it can't be overwritten by others
+			dest := JoinPath(root, "dir", "cmdline")
+			defer os.Remove(dest)
+			if err := CopyFileWithOptions(src, dest, tt.opts); err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			// the command line of the test process is the same while copying and reading
+			want, _ := ioutil.ReadFile(src)
+			got, err := ioutil.ReadFile(dest)
+			if err != nil || len(got) == 0 || string(got) != string(want) {
+				t.Errorf("CopyFileWithOptions() got %d bytes, want %d bytes, error = %v", len(got), len(want), err)
+			}
+		})
+	}
+}
+
+func Test_kernelCopyChunks_NothingCopied(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"empty.txt": emptyStr,
+		"sized.txt": "content",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name        string
+		file        string
+		wantHandled bool
+	}{
+		{"Empty source", "empty.txt", true},
+		{"Source with content", "sized.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcFile, err := os.Open(JoinPath(root, tt.file))
+			if err != nil {
+				t.Fatalf("failed to open source: %v", err)
+			}
+			defer srcFile.Close()
+
+			// the system call copies nothing like copy_file_range and sendfile do for procfs and sysfs files on some kernels
+			task := newCopyTask(context.Background(), opnCopy, nil)
+			handled, err := task.kernelCopyChunks(srcFile, srcFile.Name(), "dest", func(int) (int, error) { return 0, nil })
+			if err != nil || handled != tt.wantHandled {
+				t.Errorf("kernelCopyChunks() got handled = %v, error = %v, want %v", handled, err, tt.wantHandled)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+// +build !linux
+
+package yos
+
+import (
+	"os"
+)
+
+// kernelCopyContent copies content with the kernel strategy, none of them is supported on this platform.
+func (t *copyTask) kernelCopyContent(strategy CopyStrategy, srcFile, destFile *os.File, src, dest string, size int64) (handled bool, err error) {
+	return
+}
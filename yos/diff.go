@@ -0,0 +1,193 @@
+package yos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// diffFileModeMask is a mask for file mode bits to compare in DiffDirEntries, besides the file type.
+var diffFileModeMask = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// DirDiff represents the differences between entries of two directories, the left one and the right one.
+//
+// Each field holds the paths relative to the directories in ascending order, and descendants of a directory that exists only on one side are also listed.
+type DirDiff struct {
+	// OnlyInLeft holds entries that exist in the left directory only.
+	OnlyInLeft []string
+	// OnlyInRight holds entries that exist in the right directory only.
+	OnlyInRight []string
+	// TypeChanged holds entries that exist on both sides with different file types, e.g. a file and a directory.
+	TypeChanged []string
+	// ContentChanged holds files that exist on both sides with different content.
+	ContentChanged []string
+	// SymlinkChanged holds symbolic links that exist on both sides with different destinations.
+	SymlinkChanged []string
+	// ModeChanged holds files and directories that exist on both sides with the same type but different permission bits.
+	ModeChanged []string
+}
+
+// IsEmpty indicates whether there is no difference at all between the two directories.
+func (d *DirDiff) IsEmpty() bool {
+	return d.sameEntries() && len(d.ModeChanged) == 0
+}
+
+// sameEntries indicates whether the two directories have the same entries, the difference of permission bits is ignored.
+func (d *DirDiff) sameEntries() bool {
+	return len(d.OnlyInLeft) == 0 && len(d.OnlyInRight) == 0 && len(d.TypeChanged) == 0 &&
+		len(d.ContentChanged) == 0 && len(d.SymlinkChanged) == 0
+}
+
+// DiffDirEntries compares entries of the two directories and returns all differences found. Symbolic links other than the given paths will be not be followed, and only compares content of links.
+func DiffDirEntries(left, right string) (diff *DirDiff, err error) {
+	return DiffDirEntriesWithOptions(left, right, nil)
+}
+
+// DiffDirEntriesWithOptions compares entries of the two directories with the given options and returns all differences found.
+//
+// It behaves the same as DiffDirEntries if the options is nil, and the progress is reported after each pair of entries existing on both sides is compared.
+// Symbolic links inside the directories are followed if FollowSymlinks is set, and the other filters in the options are ignored.
+func DiffDirEntriesWithOptions(left, right string, opts *WalkOptions) (diff *DirDiff, err error) {
+	return DiffDirEntriesContext(context.Background(), left, right, opts)
+}
+
+// DiffDirEntriesContext compares entries of the two directories with the given options and context, and returns all differences found.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func DiffDirEntriesContext(ctx context.Context, left, right string, opts *WalkOptions) (diff *DirDiff, err error) {
+	return diffDirEntries(ctx, left, right, opts, false)
+}
+
+// diffDirEntries compares entries of the two directories, and stops at the first difference other than permission bits if stopEarly is set.
+func diffDirEntries(ctx context.Context, left, right string, opts *WalkOptions, stopEarly bool) (diff *DirDiff, err error) {
+	var (
+		fi1, fi2       os.FileInfo
+		path1, path2   string
+		items1, items2 map[string]*FilePathInfo
+		progress       *progressTracker
+	)
+	if opts != nil {
+		progress = newProgressTracker(opnCompare, opts.Progress)
+	}
+	// resolve paths if they're symbolic links
+	if path1, fi1, err = resolveDirInfo(left); err != nil {
+		err = opError(opnCompare, left, err)
+		return
+	}
+	if path2, fi2, err = resolveDirInfo(right); err != nil {
+		err = opError(opnCompare, right, err)
+		return
+	}
+
+	// quick check if it's the identical directory
+	diff = &DirDiff{}
+	if os.SameFile(fi1, fi2) {
+		return
+	}
+
+	if items1, err = listRelativeEntries(ctx, path1, opts); err != nil {
+		return nil, err
+	}
+	if items2, err = listRelativeEntries(ctx, path2, opts); err != nil {
+		return nil, err
+	}
+
+	var (
+		common []string
+		files  int64
+	)
+	for rel, entry1 := range items1 {
+		if _, ok := items2[rel]; !ok {
+			diff.OnlyInLeft = append(diff.OnlyInLeft, rel)
+			continue
+		}
+		common = append(common, rel)
+		if !entry1.Info.IsDir() {
+			files++
+		}
+	}
+	for rel := range items2 {
+		if _, ok := items1[rel]; !ok {
+			diff.OnlyInRight = append(diff.OnlyInRight, rel)
+		}
+	}
+	sort.Strings(diff.OnlyInLeft)
+	sort.Strings(diff.OnlyInRight)
+	sort.Strings(common)
+	if stopEarly && !diff.sameEntries() {
+		return
+	}
+	progress.setTotal(0, files)
+
+	for _, rel := range common {
+		entry1, entry2 := items1[rel], items2[rel]
+		if err = contextError(ctx, opnCompare, entry1.Path); err != nil {
+			return nil, err
+		}
+		if err = diff.compareEntry(rel, entry1, entry2); err != nil {
+			return nil, err
+		}
+		if !entry1.Info.IsDir() {
+			progress.addFile(entry1.Path, 0)
+		}
+		if stopEarly && !diff.sameEntries() {
+			return
+		}
+	}
+	return
+}
+
+// compareEntry compares the two entries with the same relative path, and records the differences found.
+func (d *DirDiff) compareEntry(rel string, entry1, entry2 *FilePathInfo) (err error) {
+	mode1, mode2 := entry1.Info.Mode(), entry2.Info.Mode()
+	if mode1&os.ModeType != mode2&os.ModeType {
+		d.TypeChanged = append(d.TypeChanged, rel)
+		return
+	}
+
+	var same bool
+	switch mode1 & os.ModeType {
+	case os.ModeSymlink:
+		// permission bits of symbolic links are not meaningful on most platforms
+		if same, err = SameSymlinkContent(entry1.Path, entry2.Path); err == nil && !same {
+			d.SymlinkChanged = append(d.SymlinkChanged, rel)
+		}
+		return
+	case 0:
+		if same, err = SameFileContent(entry1.Path, entry2.Path); err != nil {
+			return
+		} else if !same {
+			d.ContentChanged = append(d.ContentChanged, rel)
+		}
+	}
+
+	if mode1&diffFileModeMask != mode2&diffFileModeMask {
+		d.ModeChanged = append(d.ModeChanged, rel)
+	}
+	return
+}
+
+// listRelativeEntries lists all entries in the directory with the options for walking, and returns them mapped by paths relative to the directory.
+func listRelativeEntries(ctx context.Context, root string, opts *WalkOptions) (entries map[string]*FilePathInfo, err error) {
+	var (
+		items    []*FilePathInfo
+		listOpts WalkOptions
+	)
+	if opts != nil {
+		listOpts = WalkOptions{FollowSymlinks: opts.FollowSymlinks, OnLinkIssue: opts.OnLinkIssue}
+	}
+	if items, err = listCondEntries(ctx, root, &listOpts, func(string, os.FileInfo) (bool, error) { return true, nil }); err != nil {
+		return
+	}
+
+	entries = make(map[string]*FilePathInfo, len(items))
+	for _, item := range items {
+		var rel string
+		if rel, err = filepath.Rel(root, item.Path); err != nil {
+			return nil, opError(opnCompare, item.Path, err)
+		}
+		entries[rel] = item
+	}
+	return
+}
@@ -0,0 +1,166 @@
+package yos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDiffDirEntries(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"left/same.txt":         "same",
+		"left/content.txt":      "left",
+		"left/mode.txt":         "mode",
+		"left/type":             "file",
+		"left/link":             "->same.txt",
+		"left/only-left/a.txt":  "a",
+		"left/nested/b.txt":     "b",
+		"right/same.txt":        "same",
+		"right/content.txt":     "right",
+		"right/mode.txt":        "mode",
+		"right/type/":           emptyStr,
+		"right/link":            "->mode.txt",
+		"right/only-right.txt":  "c",
+		"right/nested/b.txt":    "b",
+		"right/nested/more.txt": "d",
+		"empty/":                emptyStr,
+	})
+	defer os.RemoveAll(root)
+	left, right := JoinPath(root, "left"), JoinPath(root, "right")
+	if err := os.Chmod(JoinPath(right, "mode.txt"), 0600); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		want     *DirDiff
+		wantSame bool
+		wantErr  bool
+	}{
+		{"Left is not found", JoinPath(root, "__not_found__"), right, nil, false, true},
+		{"Right is a file", left, JoinPath(left, "same.txt"), nil, false, true},
+		{"Itself", left, left, &DirDiff{}, true, false},
+		{"Different entries", left, right, &DirDiff{
+			OnlyInLeft:     []string{"only-left", JoinPath("only-left", "a.txt")},
+			OnlyInRight:    []string{JoinPath("nested", "more.txt"), "only-right.txt"},
+			TypeChanged:    []string{"type"},
+			ContentChanged: []string{"content.txt"},
+			SymlinkChanged: []string{"link"},
+			ModeChanged:    []string{"mode.txt"},
+		}, false, false},
+		{"Empty left", JoinPath(root, "empty"), JoinPath(right, "nested"), &DirDiff{
+			OnlyInRight: []string{"b.txt", "more.txt"},
+		}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffDirEntries(tt.left, tt.right)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DiffDirEntries() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffDirEntries() got = %+v, want %+v", got, tt.want)
+			}
+			if got != nil && got.IsEmpty() != tt.wantSame {
+				t.Errorf("DiffDirEntries() got IsEmpty() = %v, want %v", got.IsEmpty(), tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestDiffDirEntries_ModeOnly(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"left/file.txt":  "same",
+		"right/file.txt": "same",
+	})
+	defer os.RemoveAll(root)
+	left, right := JoinPath(root, "left"), JoinPath(root, "right")
+	if err := os.Chmod(JoinPath(right, "file.txt"), 0600); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+
+	diff, err := DiffDirEntries(left, right)
+	if err != nil {
+		t.Errorf("DiffDirEntries() error = %v", err)
+		return
+	}
+	if diff.IsEmpty() || !reflect.DeepEqual(diff.ModeChanged, []string{"file.txt"}) {
+		t.Errorf("DiffDirEntries() got = %+v, want mode changed", diff)
+	}
+	if same, err := SameDirEntries(left, right); err != nil || !same {
+		t.Errorf("SameDirEntries() got = %v, error = %v, want same", same, err)
+	}
+}
+
+func TestSameDirEntries_StopEarly(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"left/a.txt":  "left",
+		"left/b.txt":  "same",
+		"left/c.txt":  "same",
+		"right/a.txt": "right",
+		"right/b.txt": "same",
+		"right/c.txt": "same",
+		"extra/a.txt": "left",
+		"extra/b.txt": "same",
+		"extra/c.txt": "same",
+		"extra/d.txt": "extra",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name      string
+		path1     string
+		path2     string
+		wantFiles []int64
+	}{
+		{"content changed", JoinPath(root, "left"), JoinPath(root, "right"), []int64{1}},
+		{"only in one side", JoinPath(root, "left"), JoinPath(root, "extra"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var files []int64
+			opts := &WalkOptions{Progress: func(p Progress) {
+				files = append(files, p.FilesDone)
+			}}
+			same, err := SameDirEntriesWithOptions(tt.path1, tt.path2, opts)
+			if err != nil || same {
+				t.Errorf("SameDirEntriesWithOptions() got = %v, error = %v, want different", same, err)
+				return
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("SameDirEntriesWithOptions() got compared files = %v, want %v", files, tt.wantFiles)
+			}
+
+			// all the differences are still found by the diff
+			files = nil
+			if _, err = DiffDirEntriesWithOptions(tt.path1, tt.path2, opts); err != nil {
+				t.Errorf("DiffDirEntriesWithOptions() error = %v", err)
+			} else if len(files) != 3 {
+				t.Errorf("DiffDirEntriesWithOptions() got compared files = %v, want 3", files)
+			}
+		})
+	}
+}
+
+func TestDiffDirEntriesContext(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"left/file.txt":  "left",
+		"right/file.txt": "right",
+	})
+	defer os.RemoveAll(root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	diff, err := DiffDirEntriesContext(ctx, JoinPath(root, "left"), JoinPath(root, "right"), nil)
+	if !errors.Is(err, context.Canceled) || diff != nil {
+		t.Errorf("DiffDirEntriesContext() got = %v, error = %v, want context canceled", diff, err)
+		return
+	}
+	expectedErrorCheck(t, err)
+}
@@ -31,8 +31,57 @@ Basic operations:
 	| Copy        | CopyDir        | CopyFile        | CopySymlink        |
 	| Move        | MoveDir        | MoveFile        | MoveSymlink        |
 
+Operations with options:
+  - CopyFileWithOptions
+  - CopyDirWithOptions
+  - CopySymlinkWithOptions
+  - MoveFileWithOptions
+  - MoveDirWithOptions
+  - MoveSymlinkWithOptions
+  - GetDirSizeWithOptions
+  - SameDirEntriesWithOptions
+  - DiffDirEntriesWithOptions
+  - FindDuplicatesWithOptions
+
+Operations with context:
+  - CopyFileContext
+  - CopyDirContext
+  - MoveFileContext
+  - MoveDirContext
+  - ListAllContext
+  - ListFileContext
+  - ListDirContext
+  - ListSymlinkContext
+  - ListMatchContext
+  - WalkEntriesContext
+  - ListEntriesContext
+  - GetDirSizeContext
+  - IsDirEmptyContext
+  - GetDiskUsageContext
+  - GetDirStatsContext
+  - FindDuplicatesContext
+  - CreateManifestContext
+  - VerifyManifestContext
+  - WatchDirContext
+  - SameDirEntriesContext
+  - DiffDirEntriesContext
+  - SyncDirContext
+
 Miscellaneous operations:
   - ListMatch
+  - WalkEntries
+  - ListEntries
+  - DiffDirEntries
+  - SyncDir
+  - GetDiskUsage
+  - GetDirStats
+  - FindDuplicates
+  - CreateManifest
+  - ReadManifest
+  - VerifyManifest
+  - WatchDir
+  - WriteFileAtomic
+  - CreateAtomicFile
   - JoinPath
   - Exist
   - NotExist
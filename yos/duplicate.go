@@ -0,0 +1,339 @@
+package yos
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/1set/gut/yhash"
+)
+
+// partialHashSize is the number of leading bytes hashed to split files of the same size before hashing the whole content.
+const partialHashSize = 4096
+
+// DuplicateGroup represents a group of regular files with identical content.
+type DuplicateGroup struct {
+	// Size is the size in bytes of each file in the group.
+	Size int64
+	// Digest is the hex-encoded SHA-256 digest of the content, it's empty if the content is compared byte by byte.
+	Digest string
+	// Paths holds paths of the identical files in the order of walking, and the first one on each device is kept as the original if duplicates are replaced.
+	Paths []string
+}
+
+// DuplicateOptions represents the options for FindDuplicates. The zero value or nil indicates the default behavior.
+type DuplicateOptions struct {
+	// WalkOptions is used for walking through the directories, but Flag and Patterns are ignored since all regular files are checked.
+	WalkOptions
+	// IgnoreEmpty indicates whether to ignore empty files instead of grouping them together.
+	IgnoreEmpty bool
+	// SkipHardlinks indicates whether to check files sharing the same inode only once, so existing hard links are not reported as duplicates.
+	SkipHardlinks bool
+	// CompareContent indicates whether to compare the whole content byte by byte with SameFileContent instead of the SHA-256 digest.
+	CompareContent bool
+	// ReplaceWithHardlinks indicates whether to replace duplicates with hard links to the first file on the same device in each group to reclaim space.
+	// The groups are still returned with the error if some duplicates fail to be replaced.
+	ReplaceWithHardlinks bool
+}
+
+// dupCandidate represents a regular file which may have duplicates.
+type dupCandidate struct {
+	path string
+	size int64
+	key  string
+}
+
+// FindDuplicates returns groups of regular files with identical content in the given directories.
+//
+// Files are grouped by size, then by the digest of leading bytes, and finally by the SHA-256 digest of the whole content.
+// Groups are sorted by the size of files in descending order. Symbolic links are not followed unless the given path itself is a link.
+func FindDuplicates(roots ...string) (groups []*DuplicateGroup, err error) {
+	return FindDuplicatesContext(context.Background(), nil, roots...)
+}
+
+// FindDuplicatesWithOptions returns groups of regular files with identical content in the given directories with the given options.
+//
+// It behaves the same as FindDuplicates if the options is nil, and the progress is reported for each file found in the groups.
+func FindDuplicatesWithOptions(opts *DuplicateOptions, roots ...string) (groups []*DuplicateGroup, err error) {
+	return FindDuplicatesContext(context.Background(), opts, roots...)
+}
+
+// FindDuplicatesContext returns groups of regular files with identical content in the given directories with the context and options.
+//
+// It checks the context between files, and returns the error of the context wrapped in *os.PathError if it's done.
+// If ContinueOnError is set, files failed to read are left out, and the partial result is returned with PathErrors.
+func FindDuplicatesContext(ctx context.Context, opts *DuplicateOptions, roots ...string) (groups []*DuplicateGroup, err error) {
+	var (
+		dupOpts   DuplicateOptions
+		collector errorCollector
+	)
+	if opts != nil {
+		dupOpts = *opts
+	}
+	progress := newProgressTracker(opnDedupe, dupOpts.Progress)
+	walkOpts := dupOpts.WalkOptions
+	walkOpts.Progress, walkOpts.Flag, walkOpts.Patterns = nil, 0, nil
+
+	// group regular files by size
+	var (
+		sizes   []int64
+		bySize  = make(map[int64][]*dupCandidate)
+		visited = make(map[string]bool)
+		inodes  = make(map[fileInode]bool)
+	)
+	for _, root := range roots {
+		err = walkCondEntries(ctx, root, &walkOpts, func(string, os.FileInfo) (bool, error) { return true, nil }, func(entry *FilePathInfo) error {
+			info := entry.Info
+			if !isFileFi(&info) || visited[entry.Path] || (dupOpts.IgnoreEmpty && info.Size() == 0) {
+				return nil
+			}
+			visited[entry.Path] = true
+			if dupOpts.SkipHardlinks {
+				if st, ok := getFileSysStat(info); ok && st.Nlink > 1 {
+					key := fileInode{dev: st.Dev, ino: st.Ino}
+					if inodes[key] {
+						return nil
+					}
+					inodes[key] = true
+				}
+			}
+
+			size := info.Size()
+			if _, ok := bySize[size]; !ok {
+				sizes = append(sizes, size)
+			}
+			bySize[size] = append(bySize[size], &dupCandidate{path: entry.Path, size: size})
+			return nil
+		})
+		if err != nil {
+			pes, ok := err.(PathErrors)
+			if !ok {
+				return nil, err
+			}
+			for _, pe := range pes {
+				collector.add(opnDedupe, pe.Path, pe)
+			}
+			err = nil
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+
+	for _, size := range sizes {
+		files := bySize[size]
+		if len(files) < 2 {
+			continue
+		}
+		if size == 0 {
+			group := &DuplicateGroup{}
+			for _, f := range files {
+				group.Paths = append(group.Paths, f.path)
+				progress.addFile(f.path, 0)
+			}
+			if !dupOpts.CompareContent {
+				if group.Digest, err = yhash.BytesSHA256(nil); err != nil {
+					return nil, err
+				}
+			}
+			groups = append(groups, group)
+			continue
+		}
+
+		// split by digest of leading bytes, which is the full digest for small files
+		var partials [][]*dupCandidate
+		if partials, err = groupCandidates(ctx, files, &collector, dupOpts.ContinueOnError, partialDigest); err != nil {
+			return nil, err
+		}
+		for _, part := range partials {
+			var subs [][]*dupCandidate
+			switch {
+			case dupOpts.CompareContent:
+				subs, err = compareCandidates(ctx, part, &collector, dupOpts.ContinueOnError)
+				for _, f := range part {
+					f.key = emptyStr
+				}
+			case size <= partialHashSize:
+				subs = [][]*dupCandidate{part}
+			default:
+				subs, err = groupCandidates(ctx, part, &collector, dupOpts.ContinueOnError, func(path string) (string, error) {
+					return yhash.FileSHA256(path)
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for _, sub := range subs {
+				group := &DuplicateGroup{Size: size, Digest: sub[0].key}
+				for _, f := range sub {
+					group.Paths = append(group.Paths, f.path)
+					progress.addFile(f.path, f.size)
+				}
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+
+	if dupOpts.ReplaceWithHardlinks {
+		for _, group := range groups {
+			if err = replaceGroupWithHardlinks(ctx, group, &collector, dupOpts.ContinueOnError); err != nil {
+				if ctx.Err() != nil {
+					return nil, err
+				}
+				// the groups are still valid even if some duplicates are not replaced
+				return groups, err
+			}
+		}
+	}
+	err = collector.result(nil)
+	return
+}
+
+// replaceGroupWithHardlinks replaces duplicates in the group with hard links to the first file on the same device, since hard links can't cross devices.
+func replaceGroupWithHardlinks(ctx context.Context, group *DuplicateGroup, collector *errorCollector, continueOnError bool) (err error) {
+	originals := make(map[uint64]string)
+	for _, path := range group.Paths {
+		if err = contextError(ctx, opnDedupe, path); err != nil {
+			return
+		}
+		var fi os.FileInfo
+		if fi, err = os.Stat(path); err == nil {
+			st, _ := getFileSysStat(fi)
+			original, found := originals[st.Dev]
+			if !found {
+				originals[st.Dev] = path
+				continue
+			}
+			err = replaceWithHardlink(original, path)
+		} else {
+			err = opError(opnDedupe, path, err)
+		}
+		if err != nil {
+			if !continueOnError {
+				return
+			}
+			collector.add(opnDedupe, path, err)
+			err = nil
+		}
+	}
+	return
+}
+
+// groupCandidates splits the files by keys from the given function, and returns groups with more than one file in the original order.
+func groupCandidates(ctx context.Context, files []*dupCandidate, collector *errorCollector, continueOnError bool, keyFn func(path string) (string, error)) (groups [][]*dupCandidate, err error) {
+	var (
+		keys  []string
+		byKey = make(map[string][]*dupCandidate)
+	)
+	for _, f := range files {
+		if err = contextError(ctx, opnDedupe, f.path); err != nil {
+			return nil, err
+		}
+		if f.key, err = keyFn(f.path); err != nil {
+			err = opError(opnDedupe, f.path, err)
+			if !continueOnError {
+				return nil, err
+			}
+			collector.add(opnDedupe, f.path, err)
+			err = nil
+			continue
+		}
+		if _, ok := byKey[f.key]; !ok {
+			keys = append(keys, f.key)
+		}
+		byKey[f.key] = append(byKey[f.key], f)
+	}
+	for _, key := range keys {
+		if len(byKey[key]) > 1 {
+			groups = append(groups, byKey[key])
+		}
+	}
+	return
+}
+
+// compareCandidates splits the files by comparing the whole content with the first file of each group, and returns groups with more than one file.
+func compareCandidates(ctx context.Context, files []*dupCandidate, collector *errorCollector, continueOnError bool) (groups [][]*dupCandidate, err error) {
+	var all [][]*dupCandidate
+	for _, f := range files {
+		if err = contextError(ctx, opnDedupe, f.path); err != nil {
+			return nil, err
+		}
+		found := false
+		for i, group := range all {
+			var same bool
+			if same, err = SameFileContent(group[0].path, f.path); err != nil {
+				if !continueOnError {
+					return nil, err
+				}
+				collector.add(opnDedupe, f.path, err)
+				err = nil
+				found = true
+				break
+			}
+			if same {
+				all[i] = append(group, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			all = append(all, []*dupCandidate{f})
+		}
+	}
+	for _, group := range all {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return
+}
+
+// partialDigest returns the SHA-256 digest of leading bytes of the file.
+func partialDigest(path string) (digest string, err error) {
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+
+	buf := make([]byte, partialHashSize)
+	var n int
+	if n, err = io.ReadFull(file, buf); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return
+	}
+	return yhash.BytesSHA256(buf[:n])
+}
+
+// replaceWithHardlink replaces the duplicate file with a hard link to the original file atomically, it does nothing if they're already the same file.
+func replaceWithHardlink(original, duplicate string) (err error) {
+	var fi1, fi2 os.FileInfo
+	if fi1, err = os.Stat(original); err != nil {
+		return opError(opnDedupe, original, err)
+	}
+	if fi2, err = os.Lstat(duplicate); err != nil {
+		return opError(opnDedupe, duplicate, err)
+	}
+	if os.SameFile(fi1, fi2) {
+		return nil
+	}
+
+	// link to a temporary name in the same directory first, and then rename it over the duplicate
+	for {
+		temp := siblingTempPath(duplicate, "dedupe")
+		if err = os.Link(original, temp); err == nil {
+			if err = os.Rename(temp, duplicate); err != nil {
+				_ = os.Remove(temp)
+				return opError(opnDedupe, duplicate, err)
+			}
+			return nil
+		} else if !os.IsExist(err) {
+			return opError(opnDedupe, duplicate, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+//go:build !windows
+// +build !windows
+
+package yos
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicatesWithOptions_Hardlinks(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":     "same",
+		"c.txt":     "same",
+		"sub/d.txt": "same",
+		"e.txt":     "unique",
+	})
+	defer os.RemoveAll(root)
+	if err := os.Link(JoinPath(root, "a.txt"), JoinPath(root, "b.txt")); err != nil {
+		t.Fatalf("failed to create hard link: %v", err)
+	}
+
+	// existing hard links are skipped
+	groups, err := FindDuplicatesWithOptions(&DuplicateOptions{SkipHardlinks: true}, root)
+	if want := []string{JoinPath(root, "a.txt"), JoinPath(root, "c.txt"), JoinPath(root, "sub", "d.txt")}; err != nil || len(groups) != 1 || !reflect.DeepEqual(groups[0].Paths, want) {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want %v", groups, err, want)
+		return
+	}
+
+	// duplicates are replaced with hard links to the first file
+	if groups, err = FindDuplicatesWithOptions(&DuplicateOptions{ReplaceWithHardlinks: true}, root); err != nil || len(groups) != 1 || len(groups[0].Paths) != 4 {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want 4 files", groups, err)
+		return
+	}
+	orig, _ := os.Stat(JoinPath(root, "a.txt"))
+	for _, name := range []string{"b.txt", "c.txt", "sub/d.txt"} {
+		fi, err := os.Stat(JoinPath(root, name))
+		if err != nil || !os.SameFile(orig, fi) {
+			t.Errorf("FindDuplicatesWithOptions() got %s not linked, error = %v", name, err)
+		}
+	}
+	expectFileContents(t, root, map[string]string{"b.txt": "same", "sub/d.txt": "same"})
+	if entries, _ := ListAll(root); len(entries) != 6 {
+		t.Errorf("FindDuplicatesWithOptions() left temporary files: %v", entries)
+	}
+	if groups, err = FindDuplicatesWithOptions(&DuplicateOptions{SkipHardlinks: true}, root); err != nil || len(groups) != 0 {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want no duplicates", groups, err)
+	}
+}
+
+func TestFindDuplicatesWithOptions_ContinueOnError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := makeTestTree(t, map[string]string{
+		"a.txt": "same",
+		"b.txt": "same",
+		"c.txt": "same",
+	})
+	defer os.RemoveAll(root)
+	locked := JoinPath(root, "b.txt")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	defer os.Chmod(locked, 0644)
+
+	if _, err := FindDuplicates(root); err == nil {
+		t.Errorf("FindDuplicates() got no error for an unreadable file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	groups, err := FindDuplicatesWithOptions(&DuplicateOptions{WalkOptions: WalkOptions{ContinueOnError: true}}, root)
+	if pes, ok := err.(PathErrors); !ok || len(pes) != 1 || pes[0].Path != locked {
+		t.Errorf("FindDuplicatesWithOptions() got error = %v, want one for %s", err, locked)
+	}
+	if want := []string{JoinPath(root, "a.txt"), JoinPath(root, "c.txt")}; len(groups) != 1 || !reflect.DeepEqual(groups[0].Paths, want) {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, want %v", groups, want)
+	}
+}
+
+func TestFindDuplicatesWithOptions_CrossDeviceHardlinks(t *testing.T) {
+	preconditionCheck(t, "Cross-device")
+	root := makeTestTree(t, map[string]string{
+		"a.txt": "same",
+		"b.txt": "same",
+	})
+	defer os.RemoveAll(root)
+	other := JoinPath(resourceReadWriteDevice, "find_duplicates")
+	defer os.RemoveAll(other)
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, name := range []string{"c.txt", "d.txt"} {
+		if err := ioutil.WriteFile(JoinPath(other, name), []byte("same"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// duplicates are linked to the first file on the same device
+	groups, err := FindDuplicatesWithOptions(&DuplicateOptions{ReplaceWithHardlinks: true}, root, other)
+	if err != nil || len(groups) != 1 || len(groups[0].Paths) != 4 {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want 4 files", groups, err)
+		return
+	}
+	for original, linked := range map[string]string{
+		JoinPath(root, "a.txt"):  JoinPath(root, "b.txt"),
+		JoinPath(other, "c.txt"): JoinPath(other, "d.txt"),
+	} {
+		fi1, _ := os.Stat(original)
+		if fi2, err := os.Stat(linked); err != nil || !os.SameFile(fi1, fi2) {
+			t.Errorf("FindDuplicatesWithOptions() got %s not linked to %s, error = %v", linked, original, err)
+		}
+	}
+}
+
+func TestFindDuplicatesWithOptions_ReplaceError(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := makeTestTree(t, map[string]string{
+		"a.txt":        "same",
+		"locked/b.txt": "same",
+		"open/c.txt":   "same",
+	})
+	defer os.RemoveAll(root)
+	locked := JoinPath(root, "locked")
+	if err := os.Chmod(locked, 0555); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	// the groups are returned even if some duplicates can't be replaced
+	groups, err := FindDuplicatesWithOptions(&DuplicateOptions{ReplaceWithHardlinks: true}, root)
+	if err == nil || len(groups) != 1 || len(groups[0].Paths) != 3 {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want groups with an error", groups, err)
+	} else {
+		expectedErrorCheck(t, err)
+	}
+
+	groups, err = FindDuplicatesWithOptions(&DuplicateOptions{ReplaceWithHardlinks: true, WalkOptions: WalkOptions{ContinueOnError: true}}, root)
+	if pes, ok := err.(PathErrors); !ok || len(pes) != 1 || len(groups) != 1 {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want one error for %s", groups, err, locked)
+	}
+	fi1, _ := os.Stat(JoinPath(root, "a.txt"))
+	if fi2, err := os.Stat(JoinPath(root, "open", "c.txt")); err != nil || !os.SameFile(fi1, fi2) {
+		t.Errorf("FindDuplicatesWithOptions() got open/c.txt not linked, error = %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package yos
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	large := strings.Repeat("x", partialHashSize*2)
+	root := makeTestTree(t, map[string]string{
+		"a.txt":       "hello",
+		"b.txt":       "hello",
+		"c.txt":       "world",
+		"sub/d.txt":   "hello",
+		"big1.bin":    large + "1",
+		"big2.bin":    large + "2",
+		"sub/big.bin": large + "1",
+		"empty1":      emptyStr,
+		"sub/empty2":  emptyStr,
+		"link":        "->a.txt",
+		"other/e.txt": "world",
+	})
+	defer os.RemoveAll(root)
+	p := func(names ...string) []string {
+		for i, n := range names {
+			names[i] = JoinPath(root, n)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name  string
+		opts  *DuplicateOptions
+		roots []string
+		want  [][]string
+	}{
+		{"default", nil, []string{root}, [][]string{
+			p("big1.bin", "sub/big.bin"),
+			p("a.txt", "b.txt", "sub/d.txt"),
+			p("c.txt", "other/e.txt"),
+			p("empty1", "sub/empty2"),
+		}},
+		{"ignore empty", &DuplicateOptions{IgnoreEmpty: true}, []string{root}, [][]string{
+			p("big1.bin", "sub/big.bin"),
+			p("a.txt", "b.txt", "sub/d.txt"),
+			p("c.txt", "other/e.txt"),
+		}},
+		{"compare content", &DuplicateOptions{IgnoreEmpty: true, CompareContent: true}, []string{root}, [][]string{
+			p("big1.bin", "sub/big.bin"),
+			p("a.txt", "b.txt", "sub/d.txt"),
+			p("c.txt", "other/e.txt"),
+		}},
+		{"multiple roots", &DuplicateOptions{IgnoreEmpty: true}, []string{JoinPath(root, "sub"), JoinPath(root, "other"), JoinPath(root, "sub")}, nil},
+		{"excluded", &DuplicateOptions{WalkOptions: WalkOptions{Exclude: []string{"sub/"}}}, []string{root}, [][]string{
+			p("a.txt", "b.txt"),
+			p("c.txt", "other/e.txt"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := FindDuplicatesWithOptions(tt.opts, tt.roots...)
+			if err != nil {
+				t.Errorf("FindDuplicatesWithOptions() error = %v", err)
+				return
+			}
+			var got [][]string
+			for _, g := range groups {
+				got = append(got, g.Paths)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindDuplicatesWithOptions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	groups, err := FindDuplicates(root)
+	if err != nil || len(groups) != 4 {
+		t.Errorf("FindDuplicates() got = %v, error = %v", groups, err)
+		return
+	}
+	if g := groups[0]; g.Size != int64(len(large)+1) || len(g.Digest) != 64 {
+		t.Errorf("FindDuplicates() got large group = %+v", g)
+	}
+	if g := groups[3]; g.Size != 0 || g.Digest != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("FindDuplicates() got empty group = %+v", g)
+	}
+	if groups, err = FindDuplicatesWithOptions(&DuplicateOptions{CompareContent: true}, root); err != nil || groups[0].Digest != emptyStr {
+		t.Errorf("FindDuplicatesWithOptions() got = %v, error = %v, want no digest", groups, err)
+	}
+
+	// progress, errors and context
+	var files int64
+	opts := &DuplicateOptions{WalkOptions: WalkOptions{Progress: func(p Progress) { files = p.FilesDone }}}
+	if _, err = FindDuplicatesWithOptions(opts, root); err != nil || files != 9 {
+		t.Errorf("FindDuplicatesWithOptions() got files = %d, error = %v, want 9", files, err)
+	}
+	if _, err = FindDuplicates(JoinPath(root, "a.txt")); err == nil {
+		t.Errorf("FindDuplicates() got no error for a file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = FindDuplicatesContext(ctx, nil, root); err == nil {
+		t.Errorf("FindDuplicatesContext() got no error for a canceled context")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+}
@@ -1,6 +1,7 @@
 package yos
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 )
@@ -20,12 +21,26 @@ func IsFileEmpty(path string) (empty bool, err error) {
 
 // IsDirEmpty checks whether the given directory contains nothing.
 func IsDirEmpty(path string) (empty bool, err error) {
+	return IsDirEmptyContext(context.Background(), path)
+}
+
+// IsDirEmptyContext checks whether the given directory contains nothing with the context.
+//
+// It returns the error of the context wrapped in *os.PathError if it's done. Entries that can't be read make the directory not empty, since they exist anyway.
+func IsDirEmptyContext(ctx context.Context, path string) (empty bool, err error) {
 	var (
 		rootFi os.FileInfo
 		root   string
 	)
 	if root, rootFi, err = resolveDirInfo(path); err == nil {
 		err = filepath.Walk(root, func(itemPath string, itemFi os.FileInfo, errItem error) error {
+			if errCtx := contextError(ctx, opnEmpty, itemPath); errCtx != nil {
+				return errCtx
+			}
+			if errItem != nil && itemPath != root {
+				// the entry exists even if it can't be read
+				return errStepOutDir
+			}
 			if os.SameFile(rootFi, itemFi) || errItem != nil {
 				return errItem
 			}
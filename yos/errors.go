@@ -0,0 +1,54 @@
+package yos
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// PathErrors is a list of errors occurred on entries during an operation that continues on errors, and the result of the operation is partial if it's returned.
+type PathErrors []*os.PathError
+
+// Error returns messages of all the errors.
+func (e PathErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+
+	msgs := make([]string, len(e))
+	for i, pe := range e {
+		msgs[i] = pe.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// errorCollector accumulates errors on entries for operations that continue on errors, it's safe for concurrent use.
+type errorCollector struct {
+	mu   sync.Mutex
+	errs PathErrors
+}
+
+// add records the error of the entry, and wraps it in *os.PathError if it's not.
+func (c *errorCollector) add(op, path string, err error) {
+	pe, ok := err.(*os.PathError)
+	if !ok {
+		pe = opError(op, path, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, pe)
+}
+
+// result returns the given error if it's not nil, or the collected errors if there are any, otherwise nil.
+func (c *errorCollector) result(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil && len(c.errs) > 0 {
+		err = c.errs
+	}
+	return err
+}
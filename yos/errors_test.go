@@ -0,0 +1,157 @@
+package yos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestPathErrors_Error(t *testing.T) {
+	err1, err2 := opError(opnCopy, "a", errNotRegularFile), opError(opnList, "b", errIsDirectory)
+	tests := []struct {
+		name string
+		errs PathErrors
+		want string
+	}{
+		{"Empty", PathErrors{}, "no errors"},
+		{"Single", PathErrors{err1}, "copy a: not a regular file"},
+		{"Multiple", PathErrors{err1, err2}, "2 errors occurred: copy a: not a regular file; list b: is a directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.errs.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyDirWithOptions_ContinueOnError(t *testing.T) {
+	for _, workers := range []int{0, 4} {
+		t.Run(fmt.Sprintf("Workers %d", workers), func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"source/a.txt":               "a",
+				"source/b.txt":               "b",
+				"source/sub/c.txt":           "c",
+				"source/sub/d.txt":           "d",
+				"dest/source/b.txt/x/":       emptyStr,
+				"dest/source/sub/c.txt/x/":   emptyStr,
+				"dest-abort/source/b.txt/x/": emptyStr,
+			})
+			defer os.RemoveAll(root)
+			src := JoinPath(root, "source")
+
+			err := CopyDirWithOptions(src, JoinPath(root, "dest"), &CopyOptions{ContinueOnError: true, Workers: workers})
+			var errs PathErrors
+			if !errors.As(err, &errs) || len(errs) != 2 {
+				t.Errorf("CopyDirWithOptions() got error = %v, want 2 path errors", err)
+				return
+			}
+			dest := JoinPath(root, "dest", "source")
+			if errs[0].Path != JoinPath(dest, "b.txt") || errs[1].Path != JoinPath(dest, "sub", "c.txt") {
+				t.Errorf("CopyDirWithOptions() got error paths = %q, %q", errs[0].Path, errs[1].Path)
+			}
+			expectFileContents(t, dest, map[string]string{
+				"a.txt":     "a",
+				"sub/d.txt": "d",
+			})
+
+			// abort on the first error by default
+			err = CopyDirWithOptions(src, JoinPath(root, "dest-abort"), &CopyOptions{Workers: workers})
+			if err == nil || errors.As(err, &errs) {
+				t.Errorf("CopyDirWithOptions() got error = %v, want a single error", err)
+			}
+		})
+	}
+}
+
+func TestWalkOptions_ContinueOnError(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("Skipping for permissions can't be denied")
+	}
+	root := makeTestTree(t, map[string]string{
+		"a.txt":        "a",
+		"locked/b.txt": "b",
+		"open/c.txt":   "c",
+		"open/d/e.txt": "e",
+	})
+	defer os.RemoveAll(root)
+	locked := JoinPath(root, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	checkErrors := func(t *testing.T, name string, err error) {
+		var errs PathErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != locked {
+			t.Errorf("%s() got error = %v, want a path error for the locked directory", name, err)
+		}
+	}
+
+	t.Run("ListEntries", func(t *testing.T) {
+		entries, err := ListEntries(root, &WalkOptions{Flag: ListRecursive | ListIncludeFile, ContinueOnError: true})
+		checkErrors(t, "ListEntries", err)
+		if len(entries) != 3 {
+			t.Errorf("ListEntries() got %d entries, want 3", len(entries))
+		}
+		if _, err = ListEntries(root, nil); err == nil {
+			t.Errorf("ListEntries() got no error without ContinueOnError")
+		}
+	})
+
+	t.Run("WalkEntries stopped early", func(t *testing.T) {
+		var visited []string
+		err := WalkEntries(root, &WalkOptions{ContinueOnError: true}, func(entry *FilePathInfo) error {
+			visited = append(visited, entry.Path)
+			if entry.Path == JoinPath(root, "open", "c.txt") {
+				return ErrStopWalk
+			}
+			return nil
+		})
+		checkErrors(t, "WalkEntries", err)
+		if len(visited) != 3 {
+			t.Errorf("WalkEntries() got visited = %v, want stopped at open/c.txt", visited)
+		}
+		if err = WalkEntries(JoinPath(root, "open"), &WalkOptions{ContinueOnError: true}, func(*FilePathInfo) error { return ErrStopWalk }); err != nil {
+			t.Errorf("WalkEntries() got error = %v, want nil for stopping without errors", err)
+		}
+	})
+
+	t.Run("GetDirSizeWithOptions", func(t *testing.T) {
+		size, err := GetDirSizeWithOptions(root, &WalkOptions{ContinueOnError: true})
+		checkErrors(t, "GetDirSizeWithOptions", err)
+		if size != 3 {
+			t.Errorf("GetDirSizeWithOptions() got = %d, want 3", size)
+		}
+	})
+
+	t.Run("CreateManifest", func(t *testing.T) {
+		manifest, err := CreateManifest(root, &ManifestOptions{WalkOptions: WalkOptions{ContinueOnError: true}})
+		checkErrors(t, "CreateManifest", err)
+		if manifest == nil || len(manifest.Entries) != 5 {
+			t.Errorf("CreateManifest() got = %v, want a partial manifest with 5 entries", manifest)
+		}
+		if _, err = CreateManifest(root, nil); err == nil {
+			t.Errorf("CreateManifest() got no error without ContinueOnError")
+		}
+	})
+
+	t.Run("IsDirEmpty", func(t *testing.T) {
+		if empty, err := IsDirEmpty(locked); err == nil || empty {
+			t.Errorf("IsDirEmpty() got = %v, error = %v, want error", empty, err)
+		}
+
+		// entries can be listed but not read without the execute permission
+		unsearchable := JoinPath(root, "open", "d")
+		if err := os.Chmod(unsearchable, 0444); err != nil {
+			t.Fatalf("failed to change mode: %v", err)
+		}
+		defer os.Chmod(unsearchable, 0755)
+		if empty, err := IsDirEmpty(unsearchable); err != nil || empty {
+			t.Errorf("IsDirEmpty() got = %v, error = %v, want not empty", empty, err)
+		}
+	})
+}
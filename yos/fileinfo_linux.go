@@ -0,0 +1,24 @@
+package yos
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// getFileSysStat returns the system-dependent stat fields from the file info.
+func getFileSysStat(fi os.FileInfo) (st fileSysStat, ok bool) {
+	var raw *syscall.Stat_t
+	if raw, ok = fi.Sys().(*syscall.Stat_t); ok {
+		st = fileSysStat{
+			Dev:    uint64(raw.Dev),
+			Ino:    uint64(raw.Ino),
+			Nlink:  uint64(raw.Nlink),
+			Uid:    int(raw.Uid),
+			Gid:    int(raw.Gid),
+			Blocks: raw.Blocks,
+			Atime:  time.Unix(raw.Atim.Unix()),
+		}
+	}
+	return
+}
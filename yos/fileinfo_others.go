@@ -0,0 +1,12 @@
+// +build !linux,!darwin
+
+package yos
+
+import (
+	"os"
+)
+
+// getFileSysStat returns the system-dependent stat fields from the file info, it's not supported on this platform.
+func getFileSysStat(fi os.FileInfo) (st fileSysStat, ok bool) {
+	return
+}
@@ -0,0 +1,182 @@
+package yos
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pathGlob is a compiled wildcard pattern matching paths relative to a directory.
+type pathGlob struct {
+	re   *regexp.Regexp
+	segs []*regexp.Regexp
+}
+
+// compilePathGlobs expands braces in the patterns, and compiles them into path globs.
+func compilePathGlobs(patterns []string) (globs []*pathGlob, err error) {
+	for _, pattern := range patterns {
+		for _, expanded := range expandBraces(pattern) {
+			var glob *pathGlob
+			if glob, err = compilePathGlob(strings.TrimPrefix(expanded, "/")); err != nil {
+				return nil, opError(opnList, pattern, err)
+			}
+			globs = append(globs, glob)
+		}
+	}
+	return
+}
+
+// compilePathGlob compiles the wildcard pattern without braces, and each segment of it for pruning, segments of "**" are left nil.
+func compilePathGlob(pattern string) (glob *pathGlob, err error) {
+	glob = &pathGlob{}
+	if glob.re, err = regexp.Compile(globToRegexp(pattern, true)); err != nil {
+		return nil, err
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		var re *regexp.Regexp
+		if seg != "**" {
+			if re, err = regexp.Compile(globToRegexp(seg, true)); err != nil {
+				return nil, err
+			}
+		}
+		glob.segs = append(glob.segs, re)
+	}
+	return
+}
+
+// match indicates whether the relative path matches the pattern.
+func (g *pathGlob) match(rel string) bool {
+	return g.re.MatchString(rel)
+}
+
+// matchUnder indicates whether any entries under the relative directory may match the pattern.
+func (g *pathGlob) matchUnder(dir string) bool {
+	segs := g.segs
+	for _, name := range strings.Split(dir, "/") {
+		switch {
+		case len(segs) == 0:
+			// the pattern is shorter than the directory
+			return false
+		case segs[0] == nil:
+			// "**" matches any levels of directories
+			return true
+		case !segs[0].MatchString(name):
+			return false
+		}
+		segs = segs[1:]
+	}
+	return len(segs) > 0
+}
+
+// anyPathGlobUnder indicates whether any entries under the relative directory may match any of the patterns.
+func anyPathGlobUnder(globs []*pathGlob, dir string) bool {
+	for _, glob := range globs {
+		if glob.matchUnder(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands braces like "{a,b}" in the pattern into multiple patterns, nested braces are supported and braces without commas are kept as they are.
+func expandBraces(pattern string) (patterns []string) {
+	start, end, alts := findBraces(pattern)
+	if start < 0 {
+		return []string{pattern}
+	}
+	for _, alt := range alts {
+		patterns = append(patterns, expandBraces(pattern[:start]+alt+pattern[end+1:])...)
+	}
+	return
+}
+
+// findBraces returns the positions of the first pair of braces with commas at the top level in the pattern and the alternatives inside, or -1 if there is no such pair.
+func findBraces(pattern string) (start, end int, alts []string) {
+	for start = 0; start < len(pattern); start++ {
+		switch pattern[start] {
+		case '\\':
+			start++
+			continue
+		case '{':
+		default:
+			continue
+		}
+
+		var (
+			depth = 0
+			last  = start + 1
+			parts []string
+		)
+	ScanBraces:
+		for end = start; end < len(pattern); end++ {
+			switch pattern[end] {
+			case '\\':
+				end++
+			case '{':
+				depth++
+			case ',':
+				if depth == 1 {
+					parts = append(parts, pattern[last:end])
+					last = end + 1
+				}
+			case '}':
+				if depth--; depth == 0 {
+					if len(parts) > 0 {
+						return start, end, append(parts, pattern[last:end])
+					}
+					break ScanBraces
+				}
+			}
+		}
+	}
+	return -1, -1, nil
+}
+
+// globToRegexp converts the wildcard pattern with "**" to a regular expression matching paths with slashes, unanchored patterns match at any level.
+func globToRegexp(pattern string, anchored bool) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); {
+		segStart := i == 0 || pattern[i-1] == '/'
+		switch c := pattern[i]; {
+		case segStart && strings.HasPrefix(pattern[i:], "**/"):
+			// leading or middle "**/" matches zero or more directories
+			sb.WriteString("(?:.*/)?")
+			i += 3
+		case segStart && pattern[i:] == "**":
+			// trailing "**" matches everything inside
+			sb.WriteString(".*")
+			i += 2
+		case c == '*':
+			sb.WriteString("[^/]*")
+			i++
+		case c == '?':
+			sb.WriteString("[^/]")
+			i++
+		case c == '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end > 0 {
+				class := pattern[i+1 : i+1+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				sb.WriteString("[" + class + "]")
+				i += end + 2
+			} else {
+				sb.WriteString(regexp.QuoteMeta("["))
+				i++
+			}
+		case c == '\\' && i+1 < len(pattern):
+			sb.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			i += 2
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			i++
+		}
+	}
+
+	sb.WriteString("$")
+	return sb.String()
+}
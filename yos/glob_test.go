@@ -0,0 +1,121 @@
+package yos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_expandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"a.txt", []string{"a.txt"}},
+		{"*.{json,yaml}", []string{"*.json", "*.yaml"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+		{"x{a,b{c,d}}y", []string{"xay", "xbcy", "xbdy"}},
+		{"{a,}.txt", []string{"a.txt", ".txt"}},
+		{"{a}.txt", []string{"{a}.txt"}},
+		{"{a}{b,c}", []string{"{a}b", "{a}c"}},
+		{"{a,b", []string{"{a,b"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := expandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandBraces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pathGlob(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		path      string
+		wantMatch bool
+		wantUnder bool
+	}{
+		{"src/**/testdata/*.json", "src", false, true},
+		{"src/**/testdata/*.json", "lib", false, false},
+		{"src/**/testdata/*.json", "src/a/b", false, true},
+		{"src/**/testdata/*.json", "src/testdata/x.json", true, true},
+		{"src/**/testdata/*.json", "src/a/b/testdata/x.json", true, true},
+		{"src/**/testdata/*.json", "src/a/testdata/x.yaml", false, true},
+		{"a/*/c", "a/b", false, true},
+		{"a/*/c", "a/b/c", true, false},
+		{"a/*/c", "a/b/d", false, false},
+		{"a/[bc]/d", "a/c", false, true},
+		{"a/[!bc]/d", "a/c", false, false},
+		{"**", "a/b/c", true, true},
+		{"*.txt", "a", false, false},
+		{"*.txt", "a.txt", true, false},
+		{"a/?.txt", "a/b.txt", true, false},
+		{"a/?.txt", "a/bc.txt", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			glob, err := compilePathGlob(tt.pattern)
+			if err != nil {
+				t.Errorf("compilePathGlob() error = %v", err)
+				return
+			}
+			if got := glob.match(tt.path); got != tt.wantMatch {
+				t.Errorf("match() = %v, want %v", got, tt.wantMatch)
+			}
+			if got := glob.matchUnder(tt.path); got != tt.wantUnder {
+				t.Errorf("matchUnder() = %v, want %v", got, tt.wantUnder)
+			}
+		})
+	}
+}
+
+func TestListMatch_Path(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"src/testdata/a.json":          "a",
+		"src/testdata/b.yaml":          "b",
+		"src/testdata/c.txt":           "c",
+		"src/pkg/testdata/d.json":      "d",
+		"src/pkg/deep/testdata/E.JSON": "e",
+		"src/pkg/other/f.json":         "f",
+		"lib/testdata/g.json":          "g",
+		"top.json":                     "top",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name     string
+		flag     int
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{"Double stars", ListIncludeFile | ListMatchPath, []string{"src/**/testdata/*.json"}, []string{"src/pkg/testdata/d.json", "src/testdata/a.json"}, false},
+		{"Braces", ListIncludeFile | ListMatchPath, []string{"src/testdata/*.{json,yaml}"}, []string{"src/testdata/a.json", "src/testdata/b.yaml"}, false},
+		{"Lower case", ListIncludeFile | ListMatchPath | ListToLower, []string{"src/**/*.json"}, []string{"src/pkg/deep/testdata/E.JSON", "src/pkg/other/f.json", "src/pkg/testdata/d.json", "src/testdata/a.json"}, false},
+		{"Directories", ListIncludeDir | ListMatchPath, []string{"*/testdata", "src/pkg/*"}, []string{"lib/testdata", "src/pkg/deep", "src/pkg/other", "src/pkg/testdata", "src/testdata"}, false},
+		{"Leading slash", ListIncludeFile | ListMatchPath, []string{"/*.json"}, []string{"top.json"}, false},
+		{"Regular expression", ListIncludeFile | ListMatchPath | ListUseRegExp, []string{`^lib/.*\.json$`}, []string{"lib/testdata/g.json"}, false},
+		{"Malformed pattern", ListIncludeFile | ListMatchPath, []string{"src/[z-a]"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ListMatch(root, tt.flag, tt.patterns...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ListMatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			var got []string
+			for _, entry := range entries {
+				rel, _ := filepath.Rel(root, entry.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListMatch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package yos
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a compiled rule in .gitignore syntax.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRuleSet holds rules applied to entries in the base directory, which is relative to the root with slashes, or empty for the root.
+type ignoreRuleSet struct {
+	base  string
+	rules []ignoreRule
+}
+
+// excludeMatcher determines whether entries under the root should be excluded by rules in .gitignore syntax. Methods of a nil matcher exclude nothing.
+type excludeMatcher struct {
+	op       string
+	root     string
+	fileName string
+	sets     []*ignoreRuleSet
+}
+
+// newExcludeMatcher returns a matcher with the rules for the root, and the ignore files with the given name loaded from directories walked through,
+// or nil if there are neither rules nor the file name. The ignore file in the root should be loaded by the caller as well.
+func newExcludeMatcher(op, root string, rules []string, fileName string) (m *excludeMatcher, err error) {
+	if len(rules) == 0 && fileName == emptyStr {
+		return
+	}
+
+	m = &excludeMatcher{op: op, root: root, fileName: fileName}
+	var set *ignoreRuleSet
+	if set, err = parseIgnoreRules(op, emptyStr, rules); err != nil {
+		return nil, err
+	}
+	m.sets = append(m.sets, set)
+	return
+}
+
+// excluded indicates whether the entry should be excluded. The last matched rule wins, and rules in nested directories take precedence.
+func (m *excludeMatcher) excluded(path string, fi os.FileInfo) (excluded bool) {
+	if m == nil {
+		return
+	}
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil {
+		return
+	}
+	rel = filepath.ToSlash(rel)
+
+	isDir := fi.IsDir()
+	for _, set := range m.sets {
+		subRel := rel
+		if set.base != emptyStr {
+			if !strings.HasPrefix(rel, set.base+"/") {
+				continue
+			}
+			subRel = rel[len(set.base)+1:]
+		}
+		for _, rule := range set.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(subRel) {
+				excluded = !rule.negate
+			}
+		}
+	}
+	return
+}
+
+// loadDir loads rules from the ignore file in the directory if it exists.
+func (m *excludeMatcher) loadDir(dir string) (err error) {
+	if m == nil || m.fileName == emptyStr {
+		return
+	}
+
+	var file *os.File
+	if file, err = os.Open(JoinPath(dir, m.fileName)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		return opError(m.op, file.Name(), err)
+	}
+
+	var base string
+	if base, err = filepath.Rel(m.root, dir); err != nil {
+		return opError(m.op, dir, err)
+	} else if base == "." {
+		base = emptyStr
+	}
+
+	var set *ignoreRuleSet
+	if set, err = parseIgnoreRules(m.op, filepath.ToSlash(base), lines); err == nil && len(set.rules) > 0 {
+		m.sets = append(m.sets, set)
+	}
+	return
+}
+
+// parseIgnoreRules compiles lines in .gitignore syntax into a rule set for the base directory, blank lines and comments are ignored.
+func parseIgnoreRules(op, base string, lines []string) (set *ignoreRuleSet, err error) {
+	set = &ignoreRuleSet{base: base}
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		// trailing spaces are ignored unless they're escaped
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == emptyStr || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == emptyStr {
+			continue
+		}
+
+		// patterns with a slash at the beginning or in the middle are relative to the base directory, otherwise they match at any level
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if rule.re, err = regexp.Compile(globToRegexp(line, anchored)); err != nil {
+			return nil, opError(op, line, err)
+		}
+		set.rules = append(set.rules, rule)
+	}
+	return
+}
@@ -0,0 +1,181 @@
+package yos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+)
+
+type fakeDirInfo struct {
+	os.FileInfo
+	dir bool
+}
+
+func (f fakeDirInfo) IsDir() bool { return f.dir }
+
+func Test_excludeMatcher(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"No rules", nil, "a.txt", false, false},
+		{"Comment and blank", []string{"# a.txt", "", "   "}, "a.txt", false, false},
+		{"Name at root", []string{"a.txt"}, "a.txt", false, true},
+		{"Name at any level", []string{"a.txt"}, "x/y/a.txt", false, true},
+		{"Name mismatch", []string{"a.txt"}, "b.txt", false, false},
+		{"Wildcard", []string{"*.log"}, "x/debug.log", false, true},
+		{"Wildcard not crossing slash", []string{"x*.log"}, "x/debug.log", false, false},
+		{"Question mark", []string{"?.txt"}, "a.txt", false, true},
+		{"Character class", []string{"[ab].txt"}, "b.txt", false, true},
+		{"Negated character class", []string{"[!ab].txt"}, "b.txt", false, false},
+		{"Directory only for directory", []string{"build/"}, "x/build", true, true},
+		{"Directory only for file", []string{"build/"}, "x/build", false, false},
+		{"Anchored at root", []string{"/a.txt"}, "a.txt", false, true},
+		{"Anchored not nested", []string{"/a.txt"}, "x/a.txt", false, false},
+		{"Anchored with middle slash", []string{"x/a.txt"}, "x/a.txt", false, true},
+		{"Anchored with middle slash not nested", []string{"x/a.txt"}, "y/x/a.txt", false, false},
+		{"Leading double stars", []string{"**/a.txt"}, "x/y/a.txt", false, true},
+		{"Leading double stars at root", []string{"**/a.txt"}, "a.txt", false, true},
+		{"Middle double stars", []string{"x/**/a.txt"}, "x/y/z/a.txt", false, true},
+		{"Middle double stars for zero directory", []string{"x/**/a.txt"}, "x/a.txt", false, true},
+		{"Trailing double stars", []string{"x/**"}, "x/y/a.txt", false, true},
+		{"Trailing double stars not for itself", []string{"x/**"}, "x", true, false},
+		{"Negation", []string{"*.txt", "!a.txt"}, "a.txt", false, false},
+		{"Negation overridden", []string{"!a.txt", "*.txt"}, "a.txt", false, true},
+		{"Escaped hash", []string{`\#a.txt`}, "#a.txt", false, true},
+		{"Escaped exclamation", []string{`\!a.txt`}, "!a.txt", false, true},
+		{"Trailing spaces", []string{"a.txt   "}, "a.txt", false, true},
+		{"Escaped dot", []string{"a.txt"}, "aatxt", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newExcludeMatcher(opnList, "root", tt.rules, emptyStr)
+			if err != nil {
+				t.Errorf("newExcludeMatcher() error = %v", err)
+				return
+			}
+			if got := m.excluded(filepath.Join("root", filepath.FromSlash(tt.path)), fakeDirInfo{dir: tt.isDir}); got != tt.want {
+				t.Errorf("excluded(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeRules(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/.gitignore":          "*.log\n/build/\n!keep.log\n",
+		"source/a.txt":               "a",
+		"source/a.log":               "log",
+		"source/keep.log":            "keep",
+		"source/build/out.bin":       "out",
+		"source/node_modules/m.js":   "m",
+		"source/sub/.gitignore":      "b.txt\n!c.log\n",
+		"source/sub/b.txt":           "b",
+		"source/sub/c.log":           "c",
+		"source/sub/d.txt":           "d",
+		"source/sub/build/e.txt":     "e",
+		"source/sub/node_modules/n/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+	wantFiles := []string{".gitignore", "a.txt", "keep.log", "sub/.gitignore", "sub/build/e.txt", "sub/c.log", "sub/d.txt"}
+	opts := &WalkOptions{Flag: ListRecursive | ListIncludeFile, Exclude: []string{"node_modules/"}, IgnoreFileName: ".gitignore"}
+
+	t.Run("ListEntries", func(t *testing.T) {
+		entries, err := ListEntries(srcRoot, opts)
+		if err != nil {
+			t.Errorf("ListEntries() error = %v", err)
+			return
+		}
+		var got []string
+		for _, entry := range entries {
+			rel, _ := filepath.Rel(srcRoot, entry.Path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, wantFiles) {
+			t.Errorf("ListEntries() got = %v, want %v", got, wantFiles)
+		}
+	})
+
+	t.Run("GetDirSizeWithOptions", func(t *testing.T) {
+		size, err := GetDirSizeWithOptions(srcRoot, opts)
+		if err != nil {
+			t.Errorf("GetDirSizeWithOptions() error = %v", err)
+			return
+		}
+		want := int64(len("*.log\n/build/\n!keep.log\n") + len("a") + len("keep") + len("b.txt\n!c.log\n") + len("e") + len("c") + len("d"))
+		if size != want {
+			t.Errorf("GetDirSizeWithOptions() got = %v, want %v", size, want)
+		}
+	})
+
+	t.Run("CopyDirWithOptions", func(t *testing.T) {
+		for _, workers := range []int{0, 4} {
+			destRoot := JoinPath(root, "dest", "copied")
+			_ = os.RemoveAll(JoinPath(root, "dest"))
+			_ = os.MkdirAll(JoinPath(root, "dest"), defaultDirectoryPermMode)
+			var files int64
+			err := CopyDirWithOptions(srcRoot, destRoot, &CopyOptions{
+				Exclude:        opts.Exclude,
+				IgnoreFileName: opts.IgnoreFileName,
+				Workers:        workers,
+				Progress:       func(p Progress) { atomic.StoreInt64(&files, p.FilesTotal) },
+			})
+			if err != nil {
+				t.Errorf("CopyDirWithOptions() error = %v", err)
+				return
+			}
+			entries, _ := ListFile(destRoot)
+			var got []string
+			for _, entry := range entries {
+				rel, _ := filepath.Rel(destRoot, entry.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, wantFiles) {
+				t.Errorf("CopyDirWithOptions() got = %v, want %v", got, wantFiles)
+			}
+			if files != int64(len(wantFiles)) {
+				t.Errorf("CopyDirWithOptions() got total files = %v, want %v", files, len(wantFiles))
+			}
+			if Exist(JoinPath(destRoot, "node_modules")) || Exist(JoinPath(destRoot, "sub", "node_modules")) {
+				t.Errorf("CopyDirWithOptions() got excluded directories copied")
+			}
+		}
+	})
+
+	t.Run("SyncDir", func(t *testing.T) {
+		destRoot := JoinPath(root, "synced")
+		_ = os.MkdirAll(JoinPath(destRoot, "build"), defaultDirectoryPermMode)
+		if err := ioutil.WriteFile(JoinPath(destRoot, "build", "old.bin"), []byte("old"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := SyncDir(srcRoot, destRoot, &SyncOptions{CopyOptions: CopyOptions{Exclude: opts.Exclude, IgnoreFileName: opts.IgnoreFileName}, Delete: true}); err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		if !Exist(JoinPath(destRoot, "build", "old.bin")) {
+			t.Errorf("SyncDir() got excluded entry deleted")
+		}
+		if Exist(JoinPath(destRoot, "a.log")) || !Exist(JoinPath(destRoot, "keep.log")) {
+			t.Errorf("SyncDir() got wrong entries synchronized")
+		}
+	})
+
+	t.Run("Malformed rule", func(t *testing.T) {
+		_, err := ListEntries(srcRoot, &WalkOptions{Exclude: []string{"[z-a].txt"}})
+		if err == nil {
+			t.Errorf("ListEntries() got no error, want error")
+			return
+		}
+		expectedErrorCheck(t, err)
+	})
+}
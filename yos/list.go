@@ -1,6 +1,7 @@
 package yos
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,29 +17,61 @@ type FilePathInfo struct {
 // ListAll returns a list of all entries in the given directory in lexical order. The given directory is not included in the list.
 //
 // It searches recursively, but symbolic links other than the given path will be not be followed.
+// ListEntries with nil options lists the same entries, and its options add depth limits, exclusions, following symbolic links and continuing on errors.
 func ListAll(root string) (entries []*FilePathInfo, err error) {
-	return listCondEntries(root, func(info os.FileInfo) (bool, error) { return true, nil })
+	return ListAllContext(context.Background(), root)
+}
+
+// ListAllContext returns a list of all entries in the given directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListAllContext(ctx context.Context, root string) (entries []*FilePathInfo, err error) {
+	return listCondEntries(ctx, root, nil, func(_ string, info os.FileInfo) (bool, error) { return true, nil })
 }
 
 // ListFile returns a list of file entries in the given directory in lexical order. The given directory is not included in the list.
 //
 // It searches recursively, but symbolic links other than the given path will be not be followed.
+// ListEntries with &WalkOptions{Flag: ListRecursive | ListIncludeFile} lists the same entries, and more options like MaxDepth or Exclude can be set there.
 func ListFile(root string) (entries []*FilePathInfo, err error) {
-	return listCondEntries(root, func(info os.FileInfo) (bool, error) { return isFileFi(&info), nil })
+	return ListFileContext(context.Background(), root)
+}
+
+// ListFileContext returns a list of file entries in the given directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListFileContext(ctx context.Context, root string) (entries []*FilePathInfo, err error) {
+	return listCondEntries(ctx, root, nil, func(_ string, info os.FileInfo) (bool, error) { return isFileFi(&info), nil })
 }
 
 // ListSymlink returns a list of symbolic link entries in the given directory in lexical order. The given directory is not included in the list.
 //
 // It searches recursively, but symbolic links other than the given path will be not be followed.
+// ListEntries with &WalkOptions{Flag: ListRecursive | ListIncludeSymlink} lists the same entries, and more options like MaxDepth or Exclude can be set there.
 func ListSymlink(root string) (entries []*FilePathInfo, err error) {
-	return listCondEntries(root, func(info os.FileInfo) (bool, error) { return isSymlinkFi(&info), nil })
+	return ListSymlinkContext(context.Background(), root)
+}
+
+// ListSymlinkContext returns a list of symbolic link entries in the given directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListSymlinkContext(ctx context.Context, root string) (entries []*FilePathInfo, err error) {
+	return listCondEntries(ctx, root, nil, func(_ string, info os.FileInfo) (bool, error) { return isSymlinkFi(&info), nil })
 }
 
 // ListDir returns a list of nested directory entries in the given directory in lexical order. The given directory is not included in the list.
 //
 // It searches recursively, but symbolic links other than the given path will be not be followed.
+// ListEntries with &WalkOptions{Flag: ListRecursive | ListIncludeDir} lists the same entries, and more options like MaxDepth or Exclude can be set there.
 func ListDir(root string) (entries []*FilePathInfo, err error) {
-	return listCondEntries(root, func(info os.FileInfo) (bool, error) { return isDirFi(&info), nil })
+	return ListDirContext(context.Background(), root)
+}
+
+// ListDirContext returns a list of nested directory entries in the given directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListDirContext(ctx context.Context, root string) (entries []*FilePathInfo, err error) {
+	return listCondEntries(ctx, root, nil, func(_ string, info os.FileInfo) (bool, error) { return isDirFi(&info), nil })
 }
 
 // The flags are used by the ListMatch method.
@@ -55,6 +88,10 @@ const (
 	ListIncludeFile
 	// ListIncludeSymlink indicates ListMatch to include matched symbolic link in the returned list.
 	ListIncludeSymlink
+	// ListMatchPath indicates ListMatch to match patterns against paths relative to the directory with slashes instead of file names.
+	// Wildcard patterns support "**" for any levels of directories and braces like "{a,b}" for alternatives, and directories that can't lead to any matches are not walked into.
+	// Directories are listed recursively with this flag, and ListRecursive is not required.
+	ListMatchPath
 )
 
 const (
@@ -71,33 +108,68 @@ const (
 // There are two types of patterns are supported:
 //   1) wildcard described in filepath.Match(), this is default;
 //   2) regular expression accepted by google/RE2, use the ListUseRegExp flag to enable;
+//
+// Both types of patterns can be matched against relative paths instead of file names with the ListMatchPath flag, e.g. "src/**/testdata/*.{json,yaml}".
+//
+// ListEntries accepts the same flag and patterns in WalkOptions, along with depth limits, exclusions, following symbolic links and continuing on errors.
 func ListMatch(root string, flag int, patterns ...string) (entries []*FilePathInfo, err error) {
+	return ListMatchContext(context.Background(), root, flag, patterns...)
+}
+
+// ListMatchContext returns a list of directory entries that matches any given pattern in the directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListMatchContext(ctx context.Context, root string, flag int, patterns ...string) (entries []*FilePathInfo, err error) {
+	var cond funcEntryCond
+	if cond, err = newMatchCond(flag, patterns); err != nil {
+		return
+	}
+	return listCondEntries(ctx, root, nil, cond)
+}
+
+// newMatchCond returns the condition matching entries with the flags and patterns of ListMatch.
+func newMatchCond(flag int, patterns []string) (cond funcEntryCond, err error) {
 	var (
 		rePatterns   []*regexp.Regexp
+		globPatterns []*pathGlob
 		typeFlag     = flag & ListIncludeAll
 		useRegExp    = flag&ListUseRegExp != 0
 		useLowerName = flag&ListToLower != 0
+		usePath      = flag&ListMatchPath != 0
 	)
 	if useRegExp {
-		if rePatterns, err = compileRegexpList(patterns); err != nil {
-			return
-		}
+		rePatterns, err = compileRegexpList(patterns)
+	} else if usePath {
+		globPatterns, err = compilePathGlobs(patterns)
+	}
+	if err != nil {
+		return
 	}
 
-	return listCondEntries(root, func(info os.FileInfo) (ok bool, err error) {
+	cond = func(rel string, info os.FileInfo) (ok bool, err error) {
 		fileName := info.Name()
+		if usePath {
+			fileName = rel
+		}
 		if useLowerName {
 			fileName = strings.ToLower(fileName)
 		}
 
 		if isFileTypeMatched(&info, typeFlag) {
-			if useRegExp {
+			switch {
+			case useRegExp:
 				for _, pat := range rePatterns {
 					if ok = pat.MatchString(fileName); ok {
 						break
 					}
 				}
-			} else {
+			case usePath:
+				for _, pat := range globPatterns {
+					if ok = pat.match(fileName); ok {
+						break
+					}
+				}
+			default:
 				for _, pat := range patterns {
 					if ok, err = filepath.Match(pat, fileName); ok || err != nil {
 						break
@@ -106,38 +178,123 @@ func ListMatch(root string, flag int, patterns ...string) (entries []*FilePathIn
 			}
 		}
 
-		if err == nil && (flag&ListRecursive == 0) && isDirFi(&info) {
-			err = filepath.SkipDir
+		if err == nil && isDirFi(&info) {
+			switch {
+			case usePath && !useRegExp:
+				// prune directories that can't lead to any matches
+				if !anyPathGlobUnder(globPatterns, fileName) {
+					err = filepath.SkipDir
+				}
+			case usePath:
+				// walk into all directories for regular expressions
+			case flag&ListRecursive == 0:
+				err = filepath.SkipDir
+			}
 		}
 		return
+	}
+	return
+}
+
+// listCondEntries returns a list of conditional directory entries, entries excluded by rules in the options are skipped.
+func listCondEntries(ctx context.Context, root string, opts *WalkOptions, cond funcEntryCond) (entries []*FilePathInfo, err error) {
+	err = walkCondEntries(ctx, root, opts, cond, func(entry *FilePathInfo) error {
+		entries = append(entries, entry)
+		return nil
 	})
+	return
+}
+
+// walkCondEntries walks through the directory in lexical order, and calls the function for each conditional entry, entries excluded by rules in the options are skipped.
+func walkCondEntries(ctx context.Context, root string, opts *WalkOptions, cond funcEntryCond, fn WalkFunc) error {
+	return walkOpEntries(ctx, opnList, root, opts, cond, fn)
 }
 
-// listCondEntries returns a list of conditional directory entries.
-func listCondEntries(root string, cond func(os.FileInfo) (bool, error)) (entries []*FilePathInfo, err error) {
+// walkOpEntries walks through the directory like walkCondEntries, and errors are wrapped with the operation name.
+func walkOpEntries(ctx context.Context, opName, root string, opts *WalkOptions, cond funcEntryCond, fn WalkFunc) (err error) {
 	var (
-		rootFi   os.FileInfo
-		rootPath string
+		rootFi             os.FileInfo
+		rootPath           string
+		exclude            *excludeMatcher
+		minDepth, maxDepth int
+		follow             bool
+		onLinkIssue        func(issue *LinkIssue)
+		continueOnError    bool
+		collector          errorCollector
 	)
 	if rootPath, rootFi, err = resolveDirInfo(root); err != nil {
-		err = opError(opnList, root, err)
+		err = opError(opName, root, err)
 		return
 	}
+	if opts != nil {
+		minDepth, maxDepth = opts.MinDepth, opts.MaxDepth
+		follow, onLinkIssue = opts.FollowSymlinks, opts.OnLinkIssue
+		continueOnError = opts.ContinueOnError
+		if exclude, err = newExcludeMatcher(opName, rootPath, opts.Exclude, opts.IgnoreFileName); err == nil {
+			err = exclude.loadDir(rootPath)
+		}
+		if err != nil {
+			return
+		}
+	}
 
-	err = filepath.Walk(rootPath, func(itemPath string, itemFi os.FileInfo, errIn error) (errOut error) {
+	err = walkTree(rootPath, follow, onLinkIssue, func(itemPath string, itemFi os.FileInfo, errIn error) (errOut error) {
 		errOut = errIn
+		if errIn != nil && continueOnError {
+			// skip the entry can't be read
+			collector.add(opName, itemPath, errIn)
+			return nil
+		}
 		if os.SameFile(rootFi, itemFi) || errOut != nil {
 			return
 		}
-		var ok bool
-		if ok, errOut = cond(itemFi); ok {
-			entries = append(entries, &FilePathInfo{
+		if errOut = contextError(ctx, opName, itemPath); errOut != nil {
+			return
+		}
+		if exclude.excluded(itemPath, itemFi) {
+			if isDirFi(&itemFi) {
+				errOut = filepath.SkipDir
+			}
+			return
+		} else if isDirFi(&itemFi) {
+			if errOut = exclude.loadDir(itemPath); errOut != nil {
+				return
+			}
+		}
+		var (
+			ok  bool
+			rel string
+		)
+		if rel, errOut = filepath.Rel(rootPath, itemPath); errOut != nil {
+			return
+		}
+		rel = filepath.ToSlash(rel)
+		depth := strings.Count(rel, "/") + 1
+		ok, errOut = cond(rel, itemFi)
+		if depth < minDepth {
+			ok = false
+		}
+		if errOut == nil && maxDepth > 0 && depth >= maxDepth && isDirFi(&itemFi) {
+			// stop walking into directories at the maximum depth
+			errOut = filepath.SkipDir
+		}
+		if ok {
+			if errFn := fn(&FilePathInfo{
 				Path: itemPath,
 				Info: itemFi,
-			})
+			}); errFn != nil {
+				errOut = errFn
+			}
 		}
 		return
 	})
+	if err == ErrStopWalk {
+		// keep errors collected before the walk is stopped early
+		if errs := collector.result(nil); errs != nil {
+			err = errs
+		}
+	}
+	err = collector.result(err)
 	return
 }
 
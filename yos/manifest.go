@@ -0,0 +1,257 @@
+package yos
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/1set/gut/yhash"
+)
+
+// ManifestEntryType represents the type of an entry in Manifest.
+type ManifestEntryType string
+
+// Types of entries in Manifest.
+const (
+	ManifestFile    ManifestEntryType = "file"
+	ManifestDir     ManifestEntryType = "dir"
+	ManifestSymlink ManifestEntryType = "symlink"
+	ManifestOther   ManifestEntryType = "other"
+)
+
+// ManifestEntry represents the recorded state of an entry in a directory.
+type ManifestEntry struct {
+	// Path is the slash-separated path relative to the directory.
+	Path string `json:"path"`
+	// Type is the type of the entry.
+	Type ManifestEntryType `json:"type"`
+	// Mode is the file mode bits of the entry.
+	Mode os.FileMode `json:"mode"`
+	// Size is the size in bytes of the entry.
+	Size int64 `json:"size"`
+	// ModTime is the modification time of the entry.
+	ModTime time.Time `json:"mtime"`
+	// Target is the destination of the symbolic link, it's empty for the other types.
+	Target string `json:"target,omitempty"`
+	// Digest is the hex-encoded SHA-256 digest of the regular file, it's empty for the other types.
+	Digest string `json:"sha256,omitempty"`
+}
+
+// Manifest represents a snapshot of entries in a directory, and it can be serialized into JSON directly.
+type Manifest struct {
+	// Entries holds the entries in the directory in lexical order of paths.
+	Entries []*ManifestEntry `json:"entries"`
+}
+
+// ManifestOptions represents the options for CreateManifest and VerifyManifest. The zero value or nil indicates the default behavior.
+type ManifestOptions struct {
+	// WalkOptions is used for walking through the directory, but Flag, Patterns, MinDepth and MaxDepth are ignored since all entries are recorded.
+	WalkOptions
+	// CheckModTime indicates whether to report regular files and symbolic links with different modification times as modified in VerifyManifest.
+	CheckModTime bool
+}
+
+// ManifestDiff represents the differences between a directory and its manifest.
+//
+// Each field holds the slash-separated paths relative to the directory in ascending order.
+type ManifestDiff struct {
+	// Missing holds entries recorded in the manifest but not found in the directory.
+	Missing []string
+	// Extra holds entries found in the directory but not recorded in the manifest.
+	Extra []string
+	// Modified holds entries with a different type, mode, size, destination or content from the manifest.
+	Modified []string
+}
+
+// IsEmpty indicates whether the directory matches its manifest.
+func (d *ManifestDiff) IsEmpty() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Modified) == 0
+}
+
+// CreateManifest walks through the directory and records the state of all entries with SHA-256 digests of regular files.
+//
+// It behaves like the list functions if the options is nil, and the progress is reported after each entry is recorded.
+// If the given path is a symbolic link, it will be followed, but symbolic links inside the directory won't unless FollowSymlinks is set.
+func CreateManifest(root string, opts *ManifestOptions) (manifest *Manifest, err error) {
+	return CreateManifestContext(context.Background(), root, opts)
+}
+
+// CreateManifestContext walks through the directory and records the state of all entries with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+// If ContinueOnError is set, entries failed to read are left out, and the partial manifest is returned with PathErrors.
+func CreateManifestContext(ctx context.Context, root string, opts *ManifestOptions) (manifest *Manifest, err error) {
+	var (
+		rootPath  string
+		walkOpts  WalkOptions
+		items     []*FilePathInfo
+		collector errorCollector
+	)
+	if rootPath, _, err = resolveDirInfo(root); err != nil {
+		return nil, opError(opnManifest, root, err)
+	}
+	if opts != nil {
+		walkOpts = opts.WalkOptions
+	}
+	progress := newProgressTracker(opnManifest, walkOpts.Progress)
+	walkOpts.Progress, walkOpts.Flag, walkOpts.Patterns, walkOpts.MinDepth, walkOpts.MaxDepth = nil, 0, nil, 0, 0
+
+	if items, err = listCondEntries(ctx, rootPath, &walkOpts, func(string, os.FileInfo) (bool, error) { return true, nil }); err != nil {
+		// record the entries listed for errors collected
+		pes, ok := err.(PathErrors)
+		if !ok {
+			return nil, err
+		}
+		for _, pe := range pes {
+			collector.add(opnManifest, pe.Path, pe)
+		}
+	}
+	manifest = &Manifest{Entries: make([]*ManifestEntry, 0, len(items))}
+	for _, item := range items {
+		if err = contextError(ctx, opnManifest, item.Path); err != nil {
+			return nil, err
+		}
+		var entry *ManifestEntry
+		if entry, err = newManifestEntry(rootPath, item); err != nil {
+			if !walkOpts.ContinueOnError {
+				return nil, err
+			}
+			collector.add(opnManifest, item.Path, err)
+			continue
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		progress.addFile(item.Path, entry.Size)
+	}
+	sort.Slice(manifest.Entries, func(i, j int) bool { return manifest.Entries[i].Path < manifest.Entries[j].Path })
+	err = collector.result(nil)
+	return
+}
+
+// newManifestEntry records the state of the entry in the directory.
+func newManifestEntry(root string, item *FilePathInfo) (entry *ManifestEntry, err error) {
+	var rel string
+	if rel, err = filepath.Rel(root, item.Path); err != nil {
+		return nil, opError(opnManifest, item.Path, err)
+	}
+
+	info := item.Info
+	entry = &ManifestEntry{
+		Path:    filepath.ToSlash(rel),
+		Mode:    info.Mode(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	switch {
+	case isDirFi(&info):
+		entry.Type = ManifestDir
+	case isSymlinkFi(&info):
+		entry.Type = ManifestSymlink
+		if entry.Target, err = os.Readlink(item.Path); err != nil {
+			return nil, opError(opnManifest, item.Path, err)
+		}
+	case isFileFi(&info):
+		entry.Type = ManifestFile
+		if entry.Digest, err = yhash.FileSHA256(item.Path); err != nil {
+			return nil, opError(opnManifest, item.Path, err)
+		}
+	default:
+		entry.Type = ManifestOther
+	}
+	return
+}
+
+// ReadManifest reads a manifest serialized in JSON.
+func ReadManifest(r io.Reader) (manifest *Manifest, err error) {
+	manifest = &Manifest{}
+	if err = json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// WriteJSON writes the manifest in JSON.
+func (m *Manifest) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent(emptyStr, "  ")
+	return enc.Encode(m)
+}
+
+// WriteSHA256Sum writes digests of regular files in the manifest in the format of sha256sum, which can be checked by running "sha256sum -c" in the directory.
+func (m *Manifest) WriteSHA256Sum(w io.Writer) (err error) {
+	bw := bufio.NewWriter(w)
+	for _, entry := range m.Entries {
+		if entry.Type != ManifestFile {
+			continue
+		}
+		// escape the file name like sha256sum does for backslashes and newlines
+		name, prefix := entry.Path, emptyStr
+		if strings.ContainsAny(name, "\\\n") {
+			name, prefix = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name), "\\"
+		}
+		if _, err = bw.WriteString(prefix + entry.Digest + "  " + name + "\n"); err != nil {
+			return
+		}
+	}
+	return bw.Flush()
+}
+
+// VerifyManifest compares the current state of the directory with the manifest, and returns the missing, extra and modified entries.
+//
+// The options should be the same as the ones for creating the manifest, and modification times are compared only if CheckModTime is set.
+// It returns an error if the manifest is nil, or the directory can't be recorded completely even if ContinueOnError is set.
+func VerifyManifest(root string, manifest *Manifest, opts *ManifestOptions) (diff *ManifestDiff, err error) {
+	return VerifyManifestContext(context.Background(), root, manifest, opts)
+}
+
+// VerifyManifestContext compares the current state of the directory with the manifest with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func VerifyManifestContext(ctx context.Context, root string, manifest *Manifest, opts *ManifestOptions) (diff *ManifestDiff, err error) {
+	if manifest == nil {
+		return nil, opError(opnManifest, root, errNilManifest)
+	}
+	var current *Manifest
+	if current, err = CreateManifestContext(ctx, root, opts); err != nil {
+		return
+	}
+	checkModTime := opts != nil && opts.CheckModTime
+
+	recorded := make(map[string]*ManifestEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		recorded[entry.Path] = entry
+	}
+	diff = &ManifestDiff{}
+	for _, entry := range current.Entries {
+		old, ok := recorded[entry.Path]
+		if !ok {
+			diff.Extra = append(diff.Extra, entry.Path)
+			continue
+		}
+		delete(recorded, entry.Path)
+		if !entry.matches(old, checkModTime) {
+			diff.Modified = append(diff.Modified, entry.Path)
+		}
+	}
+	for path := range recorded {
+		diff.Missing = append(diff.Missing, path)
+	}
+	sort.Strings(diff.Missing)
+	return
+}
+
+// matches indicates whether the entry is the same as the recorded one.
+func (e *ManifestEntry) matches(old *ManifestEntry, checkModTime bool) bool {
+	if e.Type != old.Type || e.Mode != old.Mode || e.Target != old.Target || e.Digest != old.Digest {
+		return false
+	}
+	if e.Type == ManifestDir {
+		return true
+	}
+	return e.Size == old.Size && (!checkModTime || e.ModTime.Equal(old.ModTime))
+}
@@ -0,0 +1,158 @@
+package yos
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateManifest(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":     "hello",
+		"sub/b.txt": "world",
+		"empty/":    emptyStr,
+		"link":      "->a.txt",
+	})
+	defer os.RemoveAll(root)
+
+	manifest, err := CreateManifest(root, nil)
+	if err != nil {
+		t.Errorf("CreateManifest() error = %v", err)
+		return
+	}
+	var paths []string
+	for _, e := range manifest.Entries {
+		paths = append(paths, string(e.Type)+":"+e.Path)
+	}
+	if want := []string{"file:a.txt", "dir:empty", "symlink:link", "dir:sub", "file:sub/b.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("CreateManifest() got = %v, want %v", paths, want)
+	}
+	if e := manifest.Entries[0]; e.Size != 5 || e.Digest != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" || e.Mode.Perm() != 0644 {
+		t.Errorf("CreateManifest() got file entry = %+v", e)
+	}
+	if e := manifest.Entries[2]; e.Target != "a.txt" || e.Digest != emptyStr {
+		t.Errorf("CreateManifest() got symlink entry = %+v", e)
+	}
+
+	// serialization
+	var buf bytes.Buffer
+	if err = manifest.WriteJSON(&buf); err != nil {
+		t.Errorf("WriteJSON() error = %v", err)
+		return
+	}
+	loaded, err := ReadManifest(&buf)
+	if err != nil || len(loaded.Entries) != len(manifest.Entries) {
+		t.Errorf("ReadManifest() got = %v, error = %v", loaded, err)
+		return
+	}
+	for i, e := range loaded.Entries {
+		if o := manifest.Entries[i]; !e.matches(o, true) || e.Path != o.Path {
+			t.Errorf("ReadManifest() got entry = %+v, want %+v", e, o)
+		}
+	}
+	if _, err = ReadManifest(strings.NewReader("{")); err == nil {
+		t.Errorf("ReadManifest() got no error for malformed JSON")
+	}
+
+	buf.Reset()
+	manifest.Entries = append(manifest.Entries, &ManifestEntry{Path: "odd\\na\nme", Type: ManifestFile, Digest: "00"})
+	if err = manifest.WriteSHA256Sum(&buf); err != nil {
+		t.Errorf("WriteSHA256Sum() error = %v", err)
+	}
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  a.txt\n" +
+		"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7  sub/b.txt\n" +
+		"\\00  odd\\\\na\\nme\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteSHA256Sum() got = %q, want %q", got, want)
+	}
+
+	// errors
+	if _, err = CreateManifest(JoinPath(root, "a.txt"), nil); err == nil {
+		t.Errorf("CreateManifest() got no error for a file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = CreateManifestContext(ctx, root, nil); err == nil {
+		t.Errorf("CreateManifestContext() got no error for a canceled context")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	if _, err = VerifyManifest(root, nil, nil); err == nil {
+		t.Errorf("VerifyManifest() got no error for a nil manifest")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(root string) error
+		opts   *ManifestOptions
+		want   ManifestDiff
+	}{
+		{"unchanged", func(string) error { return nil }, nil, ManifestDiff{}},
+		{"content", func(root string) error {
+			return ioutil.WriteFile(JoinPath(root, "a.txt"), []byte("HELLO"), 0644)
+		}, nil, ManifestDiff{Modified: []string{"a.txt"}}},
+		{"mode", func(root string) error {
+			return os.Chmod(JoinPath(root, "sub"), 0700)
+		}, nil, ManifestDiff{Modified: []string{"sub"}}},
+		{"symlink", func(root string) error {
+			if err := os.Remove(JoinPath(root, "link")); err != nil {
+				return err
+			}
+			return os.Symlink("sub", JoinPath(root, "link"))
+		}, nil, ManifestDiff{Modified: []string{"link"}}},
+		{"missing and extra", func(root string) error {
+			if err := os.RemoveAll(JoinPath(root, "sub")); err != nil {
+				return err
+			}
+			return ioutil.WriteFile(JoinPath(root, "new.txt"), []byte("new"), 0644)
+		}, nil, ManifestDiff{Missing: []string{"sub", "sub/b.txt"}, Extra: []string{"new.txt"}}},
+		{"mod time ignored", func(root string) error {
+			mt := time.Now().Add(-time.Hour)
+			return os.Chtimes(JoinPath(root, "a.txt"), mt, mt)
+		}, nil, ManifestDiff{}},
+		{"mod time checked", func(root string) error {
+			mt := time.Now().Add(-time.Hour)
+			return os.Chtimes(JoinPath(root, "a.txt"), mt, mt)
+		}, &ManifestOptions{CheckModTime: true}, ManifestDiff{Modified: []string{"a.txt"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"a.txt":     "hello",
+				"sub/b.txt": "world",
+				"link":      "->a.txt",
+			})
+			defer os.RemoveAll(root)
+			manifest, err := CreateManifest(root, tt.opts)
+			if err != nil {
+				t.Errorf("CreateManifest() error = %v", err)
+				return
+			}
+			if err = tt.change(root); err != nil {
+				t.Fatalf("failed to change the directory: %v", err)
+			}
+			diff, err := VerifyManifest(root, manifest, tt.opts)
+			if err != nil {
+				t.Errorf("VerifyManifest() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(*diff, tt.want) {
+				t.Errorf("VerifyManifest() got = %+v, want %+v", *diff, tt.want)
+			}
+			if diff.IsEmpty() != reflect.DeepEqual(tt.want, ManifestDiff{}) {
+				t.Errorf("VerifyManifest() got IsEmpty() = %v", diff.IsEmpty())
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package yos
+
+import (
+	"os"
+	"time"
+)
+
+// fileSysStat holds the system-dependent stat fields of a file system entry.
+type fileSysStat struct {
+	Dev    uint64
+	Ino    uint64
+	Nlink  uint64
+	Uid    int
+	Gid    int
+	Atime  time.Time
+	Blocks int64 // number of 512-byte blocks allocated
+}
+
+// preserveMetadata applies the metadata of the source entry to the destination entry according to the options.
+func (t *copyTask) preserveMetadata(src, dest string, stat funcStatFileInfo) (err error) {
+	opts := &t.opts
+	if !(opts.PreserveTimes || opts.PreserveOwner || opts.PreserveXattrs) {
+		return
+	}
+
+	var srcInfo os.FileInfo
+	if srcInfo, err = stat(src); err != nil {
+		return opError(opnCopy, src, err)
+	}
+	isLink := isSymlinkFi(&srcInfo)
+	st, hasSys := getFileSysStat(srcInfo)
+
+	// change the owner first, and then restore the setuid and setgid bits cleared by it
+	if opts.PreserveOwner && hasSys {
+		if err = os.Lchown(dest, st.Uid, st.Gid); err != nil && !os.IsPermission(err) {
+			return opError(opnCopy, dest, err)
+		}
+		err = nil
+		if !isLink {
+			if err = os.Chmod(dest, srcInfo.Mode()); err != nil {
+				return opError(opnCopy, dest, err)
+			}
+		}
+	}
+
+	if opts.PreserveXattrs {
+		if err = copyXattrs(src, dest); err != nil {
+			return opError(opnCopy, dest, err)
+		}
+	}
+
+	// change times at last, since other changes may update them
+	if opts.PreserveTimes {
+		mtime, atime := srcInfo.ModTime(), srcInfo.ModTime()
+		if hasSys {
+			atime = st.Atime
+		}
+		if err = setEntryTimes(dest, atime, mtime, isLink); err != nil {
+			return opError(opnCopy, dest, err)
+		}
+	}
+	return
+}
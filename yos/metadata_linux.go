@@ -0,0 +1,138 @@
+package yos
+
+import (
+	"bytes"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	atFdCwd           = -0x64
+	atSymlinkNoFollow = 0x100
+)
+
+// setEntryTimes changes the access and modification times of the entry, symbolic links are not followed.
+func setEntryTimes(path string, atime, mtime time.Time, isLink bool) (err error) {
+	var (
+		p     *byte
+		flags int
+		dirFd = atFdCwd
+	)
+	if p, err = syscall.BytePtrFromString(path); err != nil {
+		return
+	}
+	if isLink {
+		flags = atSymlinkNoFollow
+	}
+
+	ts := [2]syscall.Timespec{
+		syscall.NsecToTimespec(atime.UnixNano()),
+		syscall.NsecToTimespec(mtime.UnixNano()),
+	}
+	if _, _, e := syscall.Syscall6(syscall.SYS_UTIMENSAT, uintptr(dirFd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&ts[0])), uintptr(flags), 0, 0); e != 0 {
+		err = e
+	}
+	return
+}
+
+// copyXattrs copies all extended attributes from the source entry to the destination entry, symbolic links are not followed.
+func copyXattrs(src, dest string) (err error) {
+	var names []byte
+	if names, err = readXattr(syscall.SYS_LLISTXATTR, src, emptyStr); err != nil {
+		if isXattrUnsupported(err) {
+			err = nil
+		}
+		return
+	}
+
+	var value []byte
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		if value, err = readXattr(syscall.SYS_LGETXATTR, src, attr); err != nil {
+			return
+		}
+		if err = lSetXattr(dest, attr, value); err != nil {
+			if isXattrUnsupported(err) || err == syscall.EPERM {
+				// skip the attributes not supported by the destination or require privileges
+				err = nil
+				continue
+			}
+			return
+		}
+	}
+	return
+}
+
+// isXattrUnsupported indicates whether the error is caused by the file system not supporting extended attributes.
+func isXattrUnsupported(err error) bool {
+	return err == syscall.ENOTSUP || err == syscall.EOPNOTSUPP
+}
+
+// readXattr invokes llistxattr or lgetxattr with a buffer large enough to hold the result.
+func readXattr(trap uintptr, path, attr string) (data []byte, err error) {
+	var p, a *byte
+	if p, err = syscall.BytePtrFromString(path); err != nil {
+		return
+	}
+	if trap == syscall.SYS_LGETXATTR {
+		if a, err = syscall.BytePtrFromString(attr); err != nil {
+			return
+		}
+	}
+
+	invoke := func(buf []byte) (int, error) {
+		var bp unsafe.Pointer
+		if len(buf) > 0 {
+			bp = unsafe.Pointer(&buf[0])
+		}
+		var r uintptr
+		var e syscall.Errno
+		if a == nil {
+			r, _, e = syscall.Syscall(trap, uintptr(unsafe.Pointer(p)), uintptr(bp), uintptr(len(buf)))
+		} else {
+			r, _, e = syscall.Syscall6(trap, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), uintptr(bp), uintptr(len(buf)), 0, 0)
+		}
+		if e != 0 {
+			return 0, e
+		}
+		return int(r), nil
+	}
+
+	// query the size first, and retry if the value grows between the calls
+	var size int
+	for {
+		if size, err = invoke(nil); err != nil || size == 0 {
+			return
+		}
+		data = make([]byte, size)
+		if size, err = invoke(data); err == syscall.ERANGE {
+			continue
+		} else if err == nil {
+			data = data[:size]
+		}
+		return
+	}
+}
+
+// lSetXattr sets the extended attribute of the entry, symbolic links are not followed.
+func lSetXattr(path, attr string, value []byte) (err error) {
+	var p, a *byte
+	if p, err = syscall.BytePtrFromString(path); err != nil {
+		return
+	}
+	if a, err = syscall.BytePtrFromString(attr); err != nil {
+		return
+	}
+	var vp unsafe.Pointer
+	if len(value) > 0 {
+		vp = unsafe.Pointer(&value[0])
+	}
+	if _, _, e := syscall.Syscall6(syscall.SYS_LSETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), uintptr(vp), uintptr(len(value)), 0, 0); e != 0 {
+		err = e
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package yos
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func Test_copyXattrs(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source.txt": "xattr",
+		"output/":    emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	srcPath := JoinPath(root, "source.txt")
+	attrName, attrValue := "user.yos.test", []byte("gut")
+	if err := lSetXattr(srcPath, attrName, attrValue); err != nil {
+		t.Skipf("Skipping for unsupported extended attributes: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     *CopyOptions
+		wantAttr bool
+	}{
+		{"Preserve extended attributes", &CopyOptions{PreserveXattrs: true}, true},
+		{"Ignore extended attributes", &CopyOptions{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := JoinPath(root, "output", "dest.txt")
+			if err := CopyFileWithOptions(srcPath, destPath, tt.opts); err != nil {
+				t.Errorf("CopyFileWithOptions() error = %v", err)
+				return
+			}
+			defer os.Remove(destPath)
+
+			got, err := readXattr(syscall.SYS_LGETXATTR, destPath, attrName)
+			if tt.wantAttr && (err != nil || !bytes.Equal(got, attrValue)) {
+				t.Errorf("copyXattrs() got value = %q, error = %v, want %q", got, err, attrValue)
+			} else if !tt.wantAttr && err == nil {
+				t.Errorf("copyXattrs() got value = %q, want no attribute", got)
+			}
+		})
+	}
+}
+
+func TestCopyWithOptions_PreserveOwnerModeBits(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/run.sh":       "#!/bin/sh",
+		"source/shared/a.txt": "a",
+	})
+	defer os.RemoveAll(root)
+
+	src := JoinPath(root, "source")
+	fileMode := os.ModeSetuid | os.ModeSetgid | 0755
+	dirMode := os.ModeDir | os.ModeSetgid | 0755
+	if err := os.Chmod(JoinPath(src, "run.sh"), fileMode); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	if err := os.Chmod(JoinPath(src, "shared"), dirMode); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+
+	opts := &CopyOptions{PreserveOwner: true}
+	if err := CopyFileWithOptions(JoinPath(src, "run.sh"), JoinPath(root, "run.sh"), opts); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v", err)
+	}
+	if err := CopyDirWithOptions(src, JoinPath(root, "dest"), opts); err != nil {
+		t.Fatalf("CopyDirWithOptions() error = %v", err)
+	}
+
+	for path, want := range map[string]os.FileMode{
+		JoinPath(root, "run.sh"):         fileMode,
+		JoinPath(root, "dest", "run.sh"): fileMode,
+		JoinPath(root, "dest", "shared"): dirMode,
+	} {
+		if fi, err := os.Stat(path); err != nil {
+			t.Errorf("CopyWithOptions() fail to stat %s: %v", path, err)
+		} else if fi.Mode() != want {
+			t.Errorf("CopyWithOptions() got mode = %v for %s, want %v", fi.Mode(), path, want)
+		}
+	}
+}
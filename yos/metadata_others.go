@@ -0,0 +1,21 @@
+// +build !linux
+
+package yos
+
+import (
+	"os"
+	"time"
+)
+
+// setEntryTimes changes the access and modification times of the entry, times of symbolic links are not supported on this platform.
+func setEntryTimes(path string, atime, mtime time.Time, isLink bool) (err error) {
+	if !isLink {
+		err = os.Chtimes(path, atime, mtime)
+	}
+	return
+}
+
+// copyXattrs copies all extended attributes from the source entry to the destination entry, it's not supported on this platform.
+func copyXattrs(src, dest string) (err error) {
+	return
+}
@@ -1,9 +1,18 @@
 package yos
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 )
 
+// MoveOptions represents the options for move operations. The zero value or nil indicates the default behavior.
+type MoveOptions struct {
+	// CopyOptions is used for copying entries when moving across devices, and its conflict policy applies to the destination of the move.
+	// The progress is only reported for copying across devices, since renaming is done at once, and exclusion rules and ContinueOnError are ignored.
+	CopyOptions
+}
+
 // MoveFile moves a file to a target file or directory. Symbolic links will be not be followed.
 //
 // If the target is an existing file, the target will be overwritten with the source file.
@@ -14,11 +23,25 @@ import (
 //
 // If there is an error, it will be of type *os.PathError.
 func MoveFile(src, dest string) (err error) {
+	return MoveFileWithOptions(src, dest, nil)
+}
+
+// MoveFileWithOptions moves a file to a target file or directory with the given options. Symbolic links will be not be followed.
+//
+// It behaves the same as MoveFile if the options is nil.
+func MoveFileWithOptions(src, dest string, opts *MoveOptions) (err error) {
+	return MoveFileContext(context.Background(), src, dest, opts)
+}
+
+// MoveFileContext moves a file to a target file or directory with the given options and context. Symbolic links will be not be followed.
+//
+// The context is checked before moving and while copying across devices, and the partial destination file will be removed if it's done.
+func MoveFileContext(ctx context.Context, src, dest string, opts *MoveOptions) (err error) {
 	return moveEntry(
-		src, dest,
+		ctx, src, dest, opts,
 		isFileFi, errNotRegularFile,
 		os.Remove,
-		func(src, dest string) error { return bufferCopyFile(src, dest, defaultBufferSize) })
+		(*copyTask).copyFile)
 }
 
 // MoveSymlink moves a symbolic link to a target file. It makes no attempt to read the referenced file.
@@ -31,11 +54,18 @@ func MoveFile(src, dest string) (err error) {
 //
 // If there is an error, it will be of type *os.PathError.
 func MoveSymlink(src, dest string) (err error) {
+	return MoveSymlinkWithOptions(src, dest, nil)
+}
+
+// MoveSymlinkWithOptions moves a symbolic link to a target file with the given options. It makes no attempt to read the referenced file.
+//
+// It behaves the same as MoveSymlink if the options is nil.
+func MoveSymlinkWithOptions(src, dest string, opts *MoveOptions) (err error) {
 	return moveEntry(
-		src, dest,
+		context.Background(), src, dest, opts,
 		isSymlinkFi, errNotSymlink,
 		os.Remove,
-		func(src, dest string) error { return copySymlink(src, dest) })
+		(*copyTask).copySymlink)
 }
 
 // MoveDir moves a directory to a target directory recursively. Symbolic links inside the directories will not be followed.
@@ -48,17 +78,46 @@ func MoveSymlink(src, dest string) (err error) {
 //
 // MoveDir stops and returns immediately if any error occurs, and the error will be of type *os.PathError.
 func MoveDir(src, dest string) (err error) {
+	return MoveDirWithOptions(src, dest, nil)
+}
+
+// MoveDirWithOptions moves a directory to a target directory recursively with the given options. Symbolic links inside the directories will not be followed.
+//
+// It behaves the same as MoveDir if the options is nil. Unlike CopyDirWithOptions, the conflict policy only applies to the destination directory itself, which is moved as a whole.
+func MoveDirWithOptions(src, dest string, opts *MoveOptions) (err error) {
+	return MoveDirContext(context.Background(), src, dest, opts)
+}
+
+// MoveDirContext moves a directory to a target directory recursively with the given options and context. Symbolic links inside the directories will not be followed.
+//
+// The context is checked before moving and while copying across devices, the source directory is kept and the partial destination directory will be removed if it's done.
+//
+// Across devices, the source directory is copied to a staging directory beside the target, which is renamed into place before the source is removed,
+// so the original target is restored and the source is kept intact if any step fails.
+func MoveDirContext(ctx context.Context, src, dest string, opts *MoveOptions) (err error) {
 	return moveEntry(
-		src, dest,
+		ctx, src, dest, opts,
 		isDirFi, errNotDirectory,
 		os.RemoveAll,
-		func(src, dest string) error { return copyDir(src, dest) })
+		(*copyTask).copyTree)
 }
 
 // moveEntry moves source to target by renaming or copying.
-func moveEntry(src, dest string, check funcCheckFileInfo, errMode error, remove funcRemoveEntry, copy funcCopyEntry) (err error) {
+func moveEntry(ctx context.Context, src, dest string, opts *MoveOptions, check funcCheckFileInfo, errMode error, remove funcRemoveEntry, copy funcCopyEntry) (err error) {
+	var copyOpts *CopyOptions
+	if opts != nil {
+		// exclusion rules and continuing on errors don't apply, since the source is removed as a whole after copying
+		o := opts.CopyOptions
+		o.Exclude, o.IgnoreFileName, o.ContinueOnError = nil, emptyStr, false
+		copyOpts = &o
+	}
+
 	// validate and refine paths
-	if src, dest, err = refineOpPaths(opnMove, src, dest, false); err != nil {
+	var skip bool
+	if src, dest, skip, err = refineOpPaths(opnMove, src, dest, false, copyOpts); err != nil || skip {
+		return
+	}
+	if err = contextError(ctx, opnMove, src); err != nil {
 		return
 	}
 
@@ -82,15 +141,9 @@ func moveEntry(src, dest string, check funcCheckFileInfo, errMode error, remove
 	case err == nil:
 		// pass if rename succeeds
 	case isLinkErrorCrossDevice(err):
-		// cross device move == remove dest + copy to dest + remove src
-		// remove destination file, and ignore the non-existence error
-		if err = remove(dest); err != nil && !os.IsNotExist(err) {
-			err = opError(opnMove, dest, err)
-			return
-		}
-		if err = copy(src, dest); err == nil {
-			err = remove(src)
-		}
+		task := newCopyTask(ctx, opnMove, copyOpts)
+		task.estimateTotal(src, os.Lstat)
+		err = moveAcrossDevices(task, src, dest, remove, copy)
 	case os.IsNotExist(err):
 		err = opError(opnMove, src, err)
 	case os.IsExist(err):
@@ -98,3 +151,106 @@ func moveEntry(src, dest string, check funcCheckFileInfo, errMode error, remove
 	}
 	return
 }
+
+// moveAcrossDevices moves source to target on another device transactionally, it copies the source to a staging entry beside the target,
+// flushes it to the disk, renames it into place, and then removes the source. The original target is restored and the source is kept intact if any step fails before the source is removed.
+//
+// Once the source is set aside, the move is done and no error is returned, and the hidden leftovers of the source or the original target may remain if they fail to be removed.
+func moveAcrossDevices(task *copyTask, src, dest string, remove funcRemoveEntry, copy funcCopyEntry) (err error) {
+	var staging, backup, trash string
+	if staging, err = unusedSiblingPath(dest, "staging"); err != nil {
+		return opError(opnMove, dest, err)
+	}
+	if err = copy(task, src, staging); err == nil {
+		err = syncTree(staging)
+	}
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return
+	}
+
+	// set the existing destination aside, and put it back if it fails later
+	if _, err = os.Lstat(dest); err == nil {
+		if backup, err = unusedSiblingPath(dest, "backup"); err == nil {
+			err = os.Rename(dest, backup)
+		}
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return opError(opnMove, dest, err)
+	}
+	restore := func() {
+		if backup != emptyStr {
+			_ = os.Rename(backup, dest)
+		}
+	}
+
+	if err = os.Rename(staging, dest); err == nil {
+		// the new target must be persistent before the source is removed
+		if err = syncDir(filepath.Dir(dest)); err != nil {
+			_ = os.RemoveAll(dest)
+		}
+	} else {
+		_ = os.RemoveAll(staging)
+	}
+	if err != nil {
+		restore()
+		return opError(opnMove, dest, err)
+	}
+
+	// set the source aside on its device in a single step, so it's either kept intact or gone as a whole
+	if trash, err = unusedSiblingPath(src, "trash"); err == nil {
+		err = os.Rename(src, trash)
+	}
+	if err != nil {
+		_ = os.RemoveAll(dest)
+		restore()
+		return opError(opnMove, src, err)
+	}
+
+	// the move is done, and the leftovers are removed at best effort
+	_ = remove(trash)
+	if backup != emptyStr {
+		_ = os.RemoveAll(backup)
+	}
+	return nil
+}
+
+// syncTree flushes all regular files and directories inside the given path to the disk, it's skipped on Windows like syncDir.
+func syncTree(root string) error {
+	if IsOnWindows() {
+		return nil
+	}
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return opError(opnMove, path, err)
+		case info.IsDir():
+			err = syncDir(path)
+		case isFileFi(&info):
+			var file *os.File
+			if file, err = os.Open(path); err == nil {
+				err = file.Sync()
+				_ = file.Close()
+			}
+		}
+		if err != nil {
+			err = opError(opnMove, path, err)
+		}
+		return err
+	})
+}
+
+// unusedSiblingPath returns a path of a hidden temporary entry in the same directory of the given path, which doesn't exist yet.
+func unusedSiblingPath(path, tag string) (temp string, err error) {
+	for {
+		temp = siblingTempPath(path, tag)
+		if _, err = os.Lstat(temp); os.IsNotExist(err) {
+			return temp, nil
+		} else if err != nil {
+			return emptyStr, err
+		}
+	}
+}
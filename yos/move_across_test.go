@@ -0,0 +1,164 @@
+package yos
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// expectNoSiblingTemps checks that no temporary entries of moving across devices are left in the directory.
+func expectNoSiblingTemps(t *testing.T, dir string) {
+	t.Helper()
+	for _, tag := range []string{"staging", "backup", "trash"} {
+		if matches, _ := filepath.Glob(JoinPath(dir, ".*."+tag)); len(matches) > 0 {
+			t.Errorf("got temporary entries left: %v", matches)
+		}
+	}
+}
+
+func Test_moveAcrossDevices(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{"Move to new destination", false},
+		{"Replace existing destination", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTestTree(t, map[string]string{
+				"source/a.txt":     "alpha",
+				"source/sub/b.txt": "beta",
+				"dest/old.txt":     "old",
+			})
+			defer os.RemoveAll(root)
+			src, dest := JoinPath(root, "source"), JoinPath(root, "target")
+			if tt.exists {
+				dest = JoinPath(root, "dest")
+			}
+
+			task := newCopyTask(context.Background(), opnMove, nil)
+			if err := moveAcrossDevices(task, src, dest, os.RemoveAll, (*copyTask).copyTree); err != nil {
+				t.Errorf("moveAcrossDevices() error = %v", err)
+				return
+			}
+			if Exist(src) || Exist(JoinPath(dest, "old.txt")) {
+				t.Errorf("moveAcrossDevices() got source or old destination left")
+			}
+			expectFileContents(t, dest, map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"})
+			expectNoSiblingTemps(t, root)
+		})
+	}
+}
+
+func Test_moveAcrossDevices_Rollback(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt": "alpha",
+		"dest/old.txt": "old",
+	})
+	defer os.RemoveAll(root)
+	src, dest := JoinPath(root, "source"), JoinPath(root, "dest")
+
+	// failure of copying keeps both sides
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	task := newCopyTask(ctx, opnMove, nil)
+	if err := moveAcrossDevices(task, src, dest, os.RemoveAll, (*copyTask).copyTree); err == nil {
+		t.Errorf("moveAcrossDevices() got no error for a canceled context")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	expectFileContents(t, root, map[string]string{"source/a.txt": "alpha", "dest/old.txt": "old"})
+	expectNoSiblingTemps(t, root)
+
+	if os.Geteuid() == 0 || IsOnWindows() {
+		t.Skip("permissions are not enforced for root or on Windows")
+	}
+	// failure of removing the source restores the destination
+	srcParent := JoinPath(root, "locked")
+	if err := os.Mkdir(srcParent, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.Rename(src, JoinPath(srcParent, "source")); err != nil {
+		t.Fatalf("failed to move directory: %v", err)
+	}
+	src = JoinPath(srcParent, "source")
+	if err := os.Chmod(srcParent, 0555); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	defer os.Chmod(srcParent, 0755)
+
+	task = newCopyTask(context.Background(), opnMove, nil)
+	if err := moveAcrossDevices(task, src, dest, os.RemoveAll, (*copyTask).copyTree); err == nil {
+		t.Errorf("moveAcrossDevices() got no error for a locked source")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	expectFileContents(t, root, map[string]string{"locked/source/a.txt": "alpha", "dest/old.txt": "old"})
+	if Exist(JoinPath(dest, "a.txt")) {
+		t.Errorf("moveAcrossDevices() got destination not restored")
+	}
+	expectNoSiblingTemps(t, root)
+}
+
+func TestMoveDir_CrossDeviceTransaction(t *testing.T) {
+	preconditionCheck(t, "Cross-device")
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":     "alpha",
+		"source/sub/b.txt": "beta",
+	})
+	defer os.RemoveAll(root)
+	dest := JoinPath(resourceReadWriteDevice, "move_dir_transaction")
+	defer os.RemoveAll(dest)
+	if err := os.MkdirAll(JoinPath(dest, "source"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(JoinPath(dest, "source", "stale.txt"), []byte("stale"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := MoveDirWithOptions(JoinPath(root, "source"), dest, &MoveOptions{CopyOptions{Conflict: ConflictOverwrite}}); err != nil {
+		t.Errorf("MoveDirWithOptions() error = %v", err)
+		return
+	}
+	if Exist(JoinPath(root, "source")) || Exist(JoinPath(dest, "source", "stale.txt")) {
+		t.Errorf("MoveDirWithOptions() got source or old destination left")
+	}
+	expectFileContents(t, JoinPath(dest, "source"), map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"})
+	expectNoSiblingTemps(t, root)
+	expectNoSiblingTemps(t, dest)
+}
+
+func Test_moveAcrossDevices_Leftovers(t *testing.T) {
+	if os.Geteuid() == 0 || IsOnWindows() {
+		t.Skip("permissions are not enforced for root or on Windows")
+	}
+	root := makeTestTree(t, map[string]string{
+		"source/locked/a.txt": "alpha",
+	})
+	defer os.RemoveAll(root)
+	src, dest := JoinPath(root, "source"), JoinPath(root, "target")
+	locked := JoinPath(src, "locked")
+	if err := os.Chmod(locked, 0555); err != nil {
+		t.Fatalf("failed to change mode: %v", err)
+	}
+	defer os.Chmod(JoinPath(dest, "locked"), 0755)
+
+	// the source can be set aside but not removed, which doesn't fail the move
+	task := newCopyTask(context.Background(), opnMove, nil)
+	err := moveAcrossDevices(task, src, dest, os.RemoveAll, (*copyTask).copyTree)
+	if matches, _ := filepath.Glob(JoinPath(root, ".source.*.trash")); len(matches) == 1 {
+		_ = os.Chmod(JoinPath(matches[0], "locked"), 0755)
+	} else {
+		t.Errorf("moveAcrossDevices() got leftovers = %v, want the source set aside", matches)
+	}
+	if err != nil {
+		t.Errorf("moveAcrossDevices() error = %v", err)
+	}
+	expectFileContents(t, dest, map[string]string{"locked/a.txt": "alpha"})
+	if Exist(src) {
+		t.Errorf("moveAcrossDevices() got source left")
+	}
+}
@@ -0,0 +1,85 @@
+package yos
+
+import (
+	"sync"
+)
+
+// Progress describes the current state of a long-running operation.
+type Progress struct {
+	// Op is the name of the operation, e.g. "copy", "move", "size" or "compare".
+	Op string
+	// Path is the path of the entry being processed.
+	Path string
+	// BytesDone is the number of bytes processed so far.
+	BytesDone int64
+	// BytesTotal is the estimated total number of bytes to process, or 0 if it's unknown.
+	BytesTotal int64
+	// FilesDone is the number of entries other than directories processed so far.
+	FilesDone int64
+	// FilesTotal is the estimated total number of entries other than directories to process, or 0 if it's unknown.
+	FilesTotal int64
+}
+
+// ProgressFunc is the type of the function called by operations to report the progress.
+//
+// It's called synchronously in the goroutine processing the entry, which blocks the processing, so it should return quickly.
+// If entries are processed by multiple workers, it may be called concurrently from the workers, so it must be safe for concurrent use,
+// and the reports may arrive slightly out of order.
+type ProgressFunc func(p Progress)
+
+// progressTracker accumulates the progress of an operation and reports it. Methods of a nil tracker do nothing, and it's safe for concurrent use.
+// The function is called with a snapshot of the state after the lock is released.
+type progressTracker struct {
+	mu    sync.Mutex
+	fn    ProgressFunc
+	state Progress
+}
+
+// newProgressTracker returns a tracker for the operation, or nil if the function is nil.
+func newProgressTracker(op string, fn ProgressFunc) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{
+		fn:    fn,
+		state: Progress{Op: op},
+	}
+}
+
+// setTotal sets the estimated total numbers of bytes and entries.
+func (p *progressTracker) setTotal(bytes, files int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.BytesTotal, p.state.FilesTotal = bytes, files
+}
+
+// addBytes adds the number of bytes processed for the path, and reports the progress.
+func (p *progressTracker) addBytes(path string, n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.state.Path = path
+	p.state.BytesDone += n
+	state := p.state
+	p.mu.Unlock()
+	p.fn(state)
+}
+
+// addFile adds an entry processed with the number of bytes, and reports the progress.
+func (p *progressTracker) addFile(path string, n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.state.Path = path
+	p.state.BytesDone += n
+	p.state.FilesDone++
+	state := p.state
+	p.mu.Unlock()
+	// call the function out of the lock, so a slow function doesn't serialize the workers
+	p.fn(state)
+}
@@ -0,0 +1,81 @@
+package yos
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt":         "12345",
+		"source/empty/":        emptyStr,
+		"source/sub/b.txt":     strings.Repeat("b", 1000),
+		"source/sub/link.txt":  "->b.txt",
+		"compare/a.txt":        "12345",
+		"compare/empty/":       emptyStr,
+		"compare/sub/b.txt":    strings.Repeat("b", 1000),
+		"compare/sub/link.txt": "->b.txt",
+	})
+	defer os.RemoveAll(root)
+	srcRoot := JoinPath(root, "source")
+
+	tests := []struct {
+		name      string
+		op        string
+		run       func(fn ProgressFunc) error
+		wantBytes int64
+		wantFiles int64
+		wantTotal bool
+	}{
+		{"Copy file", opnCopy, func(fn ProgressFunc) error {
+			return CopyFileWithOptions(JoinPath(srcRoot, "a.txt"), JoinPath(root, "a.txt"), &CopyOptions{Progress: fn})
+		}, 5, 1, true},
+		{"Copy directory", opnCopy, func(fn ProgressFunc) error {
+			return CopyDirWithOptions(srcRoot, JoinPath(root, "copied"), &CopyOptions{Progress: fn})
+		}, 1005, 3, true},
+		{"Copy symlink", opnCopy, func(fn ProgressFunc) error {
+			return CopySymlinkWithOptions(JoinPath(srcRoot, "sub", "link.txt"), JoinPath(root, "link.txt"), &CopyOptions{Progress: fn})
+		}, 0, 1, true},
+		{"Get directory size", opnSize, func(fn ProgressFunc) error {
+			_, err := GetDirSizeWithOptions(srcRoot, &WalkOptions{Progress: fn})
+			return err
+		}, 1005 + int64(len("b.txt")), 3, false},
+		{"Compare directories", opnCompare, func(fn ProgressFunc) error {
+			_, err := SameDirEntriesWithOptions(srcRoot, JoinPath(root, "compare"), &WalkOptions{Progress: fn})
+			return err
+		}, 0, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				last  Progress
+				calls int
+			)
+			err := tt.run(func(p Progress) {
+				if p.BytesDone < last.BytesDone || p.FilesDone < last.FilesDone {
+					t.Errorf("Progress() goes backwards: %+v -> %+v", last, p)
+				}
+				last = p
+				calls++
+			})
+			if err != nil {
+				t.Errorf("Progress() got error: %v", err)
+				return
+			}
+			if calls == 0 {
+				t.Errorf("Progress() is never called")
+				return
+			}
+			if last.Op != tt.op {
+				t.Errorf("Progress() got op = %q, want %q", last.Op, tt.op)
+			}
+			if last.BytesDone != tt.wantBytes || last.FilesDone != tt.wantFiles {
+				t.Errorf("Progress() got bytes = %d, files = %d, want bytes = %d, files = %d", last.BytesDone, last.FilesDone, tt.wantBytes, tt.wantFiles)
+			}
+			if tt.wantTotal && (last.BytesTotal != last.BytesDone || last.FilesTotal != last.FilesDone) {
+				t.Errorf("Progress() got total bytes = %d, files = %d, want the same as done", last.BytesTotal, last.FilesTotal)
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package yos
 
 import (
+	"context"
 	"os"
-	"path/filepath"
 )
 
 // GetFileSize returns the size in bytes for a regular file.
@@ -37,23 +37,34 @@ func GetSymlinkSize(path string) (size int64, err error) {
 //
 // If the given path is a symbolic link, it will be followed, but symbolic links inside the directory won't.
 func GetDirSize(path string) (size int64, err error) {
-	var (
-		rootFi os.FileInfo
-		root   string
-	)
-	if root, rootFi, err = resolveDirInfo(path); err == nil {
-		err = filepath.Walk(root, func(itemPath string, itemFi os.FileInfo, errIn error) (errOut error) {
-			errOut = errIn
-			if os.SameFile(rootFi, itemFi) || errOut != nil {
-				return
-			}
-			if isFileFi(&itemFi) || isSymlinkFi(&itemFi) {
-				size += itemFi.Size()
-			}
-			return
-		})
-	} else {
-		err = opError(opnSize, path, err)
+	return GetDirSizeWithOptions(path, nil)
+}
+
+// GetDirSizeWithOptions returns total size in bytes for all regular files and symbolic links in a directory with the given options.
+//
+// It behaves the same as GetDirSize if the options is nil, and the progress is reported after each file or symbolic link is counted.
+// Symbolic links inside the directory are followed if FollowSymlinks is set, and exclusion rules and ContinueOnError are applied as well.
+func GetDirSizeWithOptions(path string, opts *WalkOptions) (size int64, err error) {
+	return GetDirSizeContext(context.Background(), path, opts)
+}
+
+// GetDirSizeContext returns total size in bytes for all regular files and symbolic links in a directory with the given options and context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func GetDirSizeContext(ctx context.Context, path string, opts *WalkOptions) (size int64, err error) {
+	var walkOpts WalkOptions
+	if opts != nil {
+		walkOpts = *opts
 	}
+	progress := newProgressTracker(opnSize, walkOpts.Progress)
+	walkOpts.Progress, walkOpts.Flag, walkOpts.Patterns, walkOpts.MinDepth, walkOpts.MaxDepth = nil, 0, nil, 0, 0
+
+	err = walkOpEntries(ctx, opnSize, path, &walkOpts, func(string, os.FileInfo) (bool, error) { return true, nil }, func(entry *FilePathInfo) error {
+		if info := entry.Info; isFileFi(&info) || isSymlinkFi(&info) {
+			size += info.Size()
+			progress.addFile(entry.Path, info.Size())
+		}
+		return nil
+	})
 	return
 }
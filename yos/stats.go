@@ -0,0 +1,131 @@
+package yos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultStatsTop is the default number of the largest files in DirStats.
+const defaultStatsTop = 10
+
+// FileStat represents the brief information of a file in DirStats.
+type FileStat struct {
+	// Path is the path of the file.
+	Path string
+	// Size is the size in bytes of the file.
+	Size int64
+	// ModTime is the modification time of the file.
+	ModTime time.Time
+}
+
+// DirStats represents the statistics summary of entries in a directory, and it can be serialized into JSON directly.
+type DirStats struct {
+	// Files is the number of regular files.
+	Files int64
+	// Dirs is the number of nested directories.
+	Dirs int64
+	// Symlinks is the number of symbolic links.
+	Symlinks int64
+	// Others is the number of the other entries like named pipes, sockets and devices.
+	Others int64
+	// TotalSize is the total size in bytes of regular files.
+	TotalSize int64
+	// Extensions is the number of regular files for each lower-cased file extension including the dot, and the empty key is for files without extensions.
+	// Dot files like ".gitignore" are counted as files without extensions, while ".config.json" has the extension ".json".
+	Extensions map[string]int64
+	// Newest is the regular file with the latest modification time, or nil if there is no file.
+	Newest *FileStat
+	// Oldest is the regular file with the earliest modification time, or nil if there is no file.
+	Oldest *FileStat
+	// Largest holds the largest regular files in descending order of size.
+	Largest []*FileStat
+}
+
+// DirStatsOptions represents the options for GetDirStats. The zero value or nil indicates the default behavior.
+type DirStatsOptions struct {
+	// WalkOptions is used for walking through the directory, but Flag and Patterns are ignored since all entries are counted.
+	WalkOptions
+	// Top is the maximum number of the largest files to report, zero indicates 10 and a negative value indicates no such report.
+	Top int
+}
+
+// GetDirStats returns the statistics summary of entries in a directory by walking through it once.
+//
+// If the given path is a symbolic link, it will be followed, but symbolic links inside the directory won't unless FollowSymlinks is set.
+func GetDirStats(path string, opts *DirStatsOptions) (stats *DirStats, err error) {
+	return GetDirStatsContext(context.Background(), path, opts)
+}
+
+// GetDirStatsContext returns the statistics summary of entries in a directory with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func GetDirStatsContext(ctx context.Context, path string, opts *DirStatsOptions) (stats *DirStats, err error) {
+	var (
+		walkOpts WalkOptions
+		top      = defaultStatsTop
+	)
+	if opts != nil {
+		walkOpts = opts.WalkOptions
+		if opts.Top != 0 {
+			top = opts.Top
+		}
+	}
+	progress := newProgressTracker(opnSize, walkOpts.Progress)
+	walkOpts.Progress = nil
+
+	stats = &DirStats{Extensions: make(map[string]int64)}
+	err = walkCondEntries(ctx, path, &walkOpts, func(string, os.FileInfo) (bool, error) { return true, nil }, func(entry *FilePathInfo) error {
+		info := entry.Info
+		switch {
+		case isDirFi(&info):
+			stats.Dirs++
+			return nil
+		case isSymlinkFi(&info):
+			stats.Symlinks++
+		case isFileFi(&info):
+			stats.addFile(entry.Path, info, top)
+		default:
+			stats.Others++
+		}
+		progress.addFile(entry.Path, 0)
+		return nil
+	})
+	if err != nil {
+		// return the partial result for errors collected
+		if _, ok := err.(PathErrors); !ok {
+			return nil, err
+		}
+	}
+	return
+}
+
+// addFile counts the regular file, and keeps the largest files sorted.
+func (s *DirStats) addFile(path string, info os.FileInfo, top int) {
+	file := &FileStat{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+	s.Files++
+	s.TotalSize += file.Size
+	s.Extensions[strings.ToLower(nameExt(filepath.Base(path)))]++
+
+	if s.Newest == nil || file.ModTime.After(s.Newest.ModTime) {
+		s.Newest = file
+	}
+	if s.Oldest == nil || file.ModTime.Before(s.Oldest.ModTime) {
+		s.Oldest = file
+	}
+
+	if top <= 0 || (len(s.Largest) == top && file.Size <= s.Largest[top-1].Size) {
+		return
+	}
+	// insert into the sorted list, and files walked earlier come first for the same size
+	idx := sort.Search(len(s.Largest), func(i int) bool { return s.Largest[i].Size < file.Size })
+	s.Largest = append(s.Largest, nil)
+	copy(s.Largest[idx+1:], s.Largest[idx:])
+	s.Largest[idx] = file
+	if len(s.Largest) > top {
+		s.Largest = s.Largest[:top]
+	}
+}
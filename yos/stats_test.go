@@ -0,0 +1,95 @@
+package yos
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetDirStats_DotFiles(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		".gitignore":   "*.log",
+		".bashrc":      "alias ll='ls -l'",
+		".config.json": "{}",
+		"Makefile":     "all:",
+		"a.json":       "[]",
+	})
+	defer os.RemoveAll(root)
+
+	stats, err := GetDirStats(root, nil)
+	if err != nil {
+		t.Errorf("GetDirStats() error = %v", err)
+		return
+	}
+	if want := map[string]int64{"": 3, ".json": 2}; !reflect.DeepEqual(stats.Extensions, want) {
+		t.Errorf("GetDirStats() got extensions = %v, want %v", stats.Extensions, want)
+	}
+}
+
+func TestGetDirStats(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":         strings.Repeat("a", 30),
+		"b.TXT":         strings.Repeat("b", 10),
+		"c.go":          strings.Repeat("c", 50),
+		"README":        strings.Repeat("r", 20),
+		"sub/d.go":      strings.Repeat("d", 40),
+		"sub/deep/e.md": "e",
+		"empty/":        emptyStr,
+		"link":          "->a.txt",
+	})
+	defer os.RemoveAll(root)
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a.txt", "b.TXT", "c.go", "README", "sub/d.go", "sub/deep/e.md"} {
+		mt := base.Add(time.Duration(i) * time.Hour)
+		_ = os.Chtimes(JoinPath(root, name), mt, mt)
+	}
+
+	stats, err := GetDirStats(root, &DirStatsOptions{Top: 3})
+	if err != nil {
+		t.Errorf("GetDirStats() error = %v", err)
+		return
+	}
+	if stats.Files != 6 || stats.Dirs != 3 || stats.Symlinks != 1 || stats.Others != 0 || stats.TotalSize != 151 {
+		t.Errorf("GetDirStats() got counts = %+v", stats)
+	}
+	if want := map[string]int64{".txt": 2, ".go": 2, "": 1, ".md": 1}; !reflect.DeepEqual(stats.Extensions, want) {
+		t.Errorf("GetDirStats() got extensions = %v, want %v", stats.Extensions, want)
+	}
+	if stats.Oldest == nil || stats.Oldest.Path != JoinPath(root, "a.txt") || !stats.Oldest.ModTime.Equal(base) {
+		t.Errorf("GetDirStats() got oldest = %+v", stats.Oldest)
+	}
+	if stats.Newest == nil || stats.Newest.Path != JoinPath(root, "sub", "deep", "e.md") {
+		t.Errorf("GetDirStats() got newest = %+v", stats.Newest)
+	}
+	var largest []string
+	for _, f := range stats.Largest {
+		largest = append(largest, f.Path)
+	}
+	if want := []string{JoinPath(root, "c.go"), JoinPath(root, "sub", "d.go"), JoinPath(root, "a.txt")}; !reflect.DeepEqual(largest, want) {
+		t.Errorf("GetDirStats() got largest = %v, want %v", largest, want)
+	}
+	if _, err = json.Marshal(stats); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+
+	// default and disabled top lists
+	if stats, err = GetDirStats(root, nil); err != nil || len(stats.Largest) != 6 {
+		t.Errorf("GetDirStats() got error = %v, largest = %v, want all 6 files", err, stats.Largest)
+	}
+	if stats, err = GetDirStats(root, &DirStatsOptions{Top: -1}); err != nil || stats.Largest != nil {
+		t.Errorf("GetDirStats() got error = %v, largest = %v, want none", err, stats.Largest)
+	}
+
+	// empty directory and errors
+	if stats, err = GetDirStats(JoinPath(root, "empty"), nil); err != nil || stats.Files != 0 || stats.Newest != nil {
+		t.Errorf("GetDirStats() got = %+v, error = %v, want nothing", stats, err)
+	}
+	if _, err = GetDirStats(JoinPath(root, "a.txt"), nil); err == nil {
+		t.Errorf("GetDirStats() got no error for a file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+}
@@ -0,0 +1,249 @@
+package yos
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/1set/gut/ystring"
+)
+
+// SyncAction represents the kind of change made to the destination by SyncDir.
+type SyncAction int
+
+const (
+	// SyncCopy indicates an entry missing in the destination is copied from the source, and a directory is copied with all its entries.
+	SyncCopy SyncAction = iota
+	// SyncUpdate indicates an entry in the destination is replaced by the one in the source, since its content or type differs.
+	SyncUpdate
+	// SyncDelete indicates an extraneous entry in the destination is removed, and a directory is removed with all its entries.
+	SyncDelete
+)
+
+// String returns the name of the action.
+func (a SyncAction) String() string {
+	switch a {
+	case SyncCopy:
+		return "copy"
+	case SyncUpdate:
+		return "update"
+	case SyncDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// SyncChange represents a change made or planned to the destination by SyncDir.
+type SyncChange struct {
+	// Action is the kind of the change.
+	Action SyncAction
+	// Path is the path of the entry relative to the source and destination directories, it's "." for the destination directory itself.
+	Path string
+}
+
+// String returns the action and the path of the change.
+func (c SyncChange) String() string {
+	return c.Action.String() + " " + c.Path
+}
+
+// SyncOptions represents the options for SyncDir. The zero value or nil indicates the default behavior.
+//
+// Modification times are always preserved to detect changes in the next run, and the conflict policy is ignored since entries in the destination are meant to be replaced.
+// Entries excluded by rules are neither copied nor deleted.
+type SyncOptions struct {
+	CopyOptions
+	// Checksum indicates whether to compare content of files instead of size and modification time to find out changed files.
+	Checksum bool
+	// Delete indicates whether to remove entries in the destination that don't exist in the source.
+	Delete bool
+	// DryRun indicates whether to only return the planned changes without touching the destination.
+	DryRun bool
+}
+
+// syncTask holds the state of a directory synchronization.
+type syncTask struct {
+	*copyTask
+	sync    SyncOptions
+	changes []*SyncChange
+}
+
+// SyncDir synchronizes the destination directory with the source directory in one way, and returns the changes made to the destination.
+//
+// Unlike CopyDir, the destination is the mirror of the source rather than its parent, and it's created if it doesn't exist.
+// Entries in the destination are copied only if they're missing or different from the source, which is determined by size and modification time of files,
+// or content if Checksum is set. Extraneous entries in the destination are removed if Delete is set. If DryRun is set, the planned changes are returned with nothing changed.
+func SyncDir(src, dest string, opts *SyncOptions) (changes []*SyncChange, err error) {
+	return SyncDirContext(context.Background(), src, dest, opts)
+}
+
+// SyncDirContext synchronizes the destination directory with the source directory in one way with the given context, and returns the changes made to the destination.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done, changes made before are kept.
+func SyncDirContext(ctx context.Context, src, dest string, opts *SyncOptions) (changes []*SyncChange, err error) {
+	if ystring.IsBlank(src) {
+		return nil, opError(opnSync, src, errInvalidPath)
+	} else if ystring.IsBlank(dest) {
+		return nil, opError(opnSync, dest, errInvalidPath)
+	}
+
+	var (
+		srcRaw            = src
+		srcInfo, destInfo os.FileInfo
+	)
+	if src, srcInfo, err = resolveDirInfo(srcRaw); err != nil {
+		return nil, opError(opnSync, srcRaw, err)
+	}
+
+	t := &syncTask{}
+	if opts != nil {
+		t.sync = *opts
+	}
+	t.sync.PreserveTimes = true
+	t.sync.Conflict = ConflictOverwrite
+	t.copyTask = newCopyTask(ctx, opnSync, &t.sync.CopyOptions)
+	if t.exclude, err = t.newExcludeMatcher(src); err != nil {
+		return nil, err
+	}
+
+	dest = filepath.Clean(dest)
+	if destInfo, err = os.Stat(dest); err == nil {
+		if !isDirFi(&destInfo) {
+			err = opError(opnSync, dest, errNotDirectory)
+		} else if os.SameFile(srcInfo, destInfo) {
+			err = opError(opnSync, dest, errSameFile)
+		} else {
+			err = t.syncDir(src, dest, emptyStr)
+		}
+	} else if os.IsNotExist(err) {
+		// copy the whole directory if the destination doesn't exist, and its parent should exist
+		if _, err = os.Stat(filepath.Dir(dest)); err == nil {
+			err = t.apply(SyncCopy, ".", func() error { return t.copyTree(src, dest) })
+		}
+	}
+	return t.changes, t.errs.result(err)
+}
+
+// syncDir synchronizes entries of the existing destination directory with the source directory recursively.
+//nolint:gocyclo // each kind of entries on both sides needs its own handling.
+func (t *syncTask) syncDir(src, dest, rel string) (err error) {
+	var (
+		srcEntries, destEntries []os.FileInfo
+		srcInfo                 os.FileInfo
+	)
+	if srcInfo, err = os.Stat(src); err != nil {
+		return
+	}
+	if srcEntries, err = ioutil.ReadDir(src); err != nil {
+		return
+	}
+	if destEntries, err = ioutil.ReadDir(dest); err != nil {
+		return
+	}
+	if err = t.exclude.loadDir(src); err != nil {
+		return
+	}
+
+	destNames := make(map[string]os.FileInfo, len(destEntries))
+	for _, entry := range destEntries {
+		destNames[entry.Name()] = entry
+	}
+
+	for _, srcEntry := range srcEntries {
+		name := srcEntry.Name()
+		srcPath, destPath, relPath := JoinPath(src, name), JoinPath(dest, name), filepath.Join(rel, name)
+		if err = contextError(t.ctx, opnSync, srcPath); err != nil {
+			return
+		}
+		if t.exclude.excluded(srcPath, srcEntry) {
+			continue
+		}
+
+		entry := srcEntry
+		destEntry, found := destNames[name]
+		delete(destNames, name)
+		copyEntry := func() error {
+			if isDirFi(&entry) {
+				return t.copyTree(srcPath, destPath)
+			}
+			return t.copyLeaf(srcPath, destPath, entry)
+		}
+
+		srcType := srcEntry.Mode() & os.ModeType
+		switch {
+		case !found:
+			err = t.apply(SyncCopy, relPath, copyEntry)
+		case srcType != destEntry.Mode()&os.ModeType:
+			// replace the entry of another type
+			err = t.apply(SyncUpdate, relPath, func() (errIn error) {
+				if errIn = os.RemoveAll(destPath); errIn != nil {
+					return opError(opnSync, destPath, errIn)
+				}
+				return copyEntry()
+			})
+		case srcType == os.ModeDir:
+			err = t.syncDir(srcPath, destPath, relPath)
+		case srcType == os.ModeSymlink || srcType == 0:
+			var same bool
+			if same, err = t.sameEntry(srcPath, destPath, srcEntry, destEntry); err == nil && !same {
+				err = t.apply(SyncUpdate, relPath, copyEntry)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	// remove entries left in the destination
+	if t.sync.Delete {
+		for _, destEntry := range destEntries {
+			// excluded entries in the destination are protected from deletion
+			name := destEntry.Name()
+			if _, left := destNames[name]; !left || t.exclude.excluded(JoinPath(src, name), destEntry) {
+				continue
+			}
+			destPath := JoinPath(dest, name)
+			if err = t.apply(SyncDelete, filepath.Join(rel, name), func() (errIn error) {
+				if errIn = os.RemoveAll(destPath); errIn != nil {
+					errIn = opError(opnSync, destPath, errIn)
+				}
+				return
+			}); err != nil {
+				return
+			}
+		}
+	}
+
+	// apply mode and metadata to the directory, since changing entries changes times of the directory
+	if !t.sync.DryRun {
+		_ = os.Chmod(dest, srcInfo.Mode())
+		err = t.preserveMetadata(src, dest, os.Stat)
+	}
+	return
+}
+
+// sameEntry checks if the file or symbolic link in the destination is the same as the one in the source.
+func (t *syncTask) sameEntry(src, dest string, srcInfo, destInfo os.FileInfo) (same bool, err error) {
+	switch {
+	case isSymlinkFi(&srcInfo):
+		same, err = SameSymlinkContent(src, dest)
+	case srcInfo.Size() != destInfo.Size():
+		same = false
+	case t.sync.Checksum:
+		same, err = SameFileContent(src, dest)
+	default:
+		same = srcInfo.ModTime().Equal(destInfo.ModTime())
+	}
+	return
+}
+
+// apply records the change, and makes it unless it's a dry run.
+func (t *syncTask) apply(action SyncAction, rel string, change func() error) (err error) {
+	if !t.sync.DryRun {
+		if err = change(); err != nil {
+			return
+		}
+	}
+	t.changes = append(t.changes, &SyncChange{Action: action, Path: rel})
+	return
+}
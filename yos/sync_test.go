@@ -0,0 +1,178 @@
+package yos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncDir(t *testing.T) {
+	srcEntries := map[string]string{
+		"source/same.txt":       "same",
+		"source/changed.txt":    "new content",
+		"source/touched.txt":    "touch",
+		"source/new.txt":        "new",
+		"source/new-dir/a.txt":  "a",
+		"source/type":           "file now",
+		"source/link":           "->same.txt",
+		"source/nested/b.txt":   "b",
+		"source/nested/c.txt":   "c",
+		"source/nested/empty/":  emptyStr,
+		"dest/same.txt":         "same",
+		"dest/changed.txt":      "old content",
+		"dest/touched.txt":      "touch",
+		"dest/type/x.txt":       "dir before",
+		"dest/link":             "->changed.txt",
+		"dest/nested/b.txt":     "b",
+		"dest/nested/extra.txt": "extra",
+		"dest/extra-dir/d.txt":  "d",
+	}
+	wantChanges := []*SyncChange{
+		{SyncUpdate, "changed.txt"},
+		{SyncUpdate, "link"},
+		{SyncCopy, filepath.Join("nested", "c.txt")},
+		{SyncCopy, filepath.Join("nested", "empty")},
+		{SyncDelete, filepath.Join("nested", "extra.txt")},
+		{SyncCopy, "new-dir"},
+		{SyncCopy, "new.txt"},
+		{SyncUpdate, "touched.txt"},
+		{SyncUpdate, "type"},
+		{SyncDelete, "extra-dir"},
+	}
+	prepare := func(t *testing.T) (root, src, dest string) {
+		root = makeTestTree(t, srcEntries)
+		src, dest = JoinPath(root, "source"), JoinPath(root, "dest")
+		// align times of unchanged files, and make the touched one differ
+		oldTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+		for _, name := range []string{"same.txt", "touched.txt", "nested/b.txt"} {
+			_ = os.Chtimes(JoinPath(src, name), oldTime, oldTime)
+			_ = os.Chtimes(JoinPath(dest, name), oldTime, oldTime)
+		}
+		newTime := oldTime.Add(time.Hour)
+		_ = os.Chtimes(JoinPath(src, "touched.txt"), newTime, newTime)
+		return
+	}
+
+	t.Run("Dry run", func(t *testing.T) {
+		root, src, dest := prepare(t)
+		defer os.RemoveAll(root)
+		before, _ := DiffDirEntries(src, dest)
+
+		changes, err := SyncDir(src, dest, &SyncOptions{Delete: true, DryRun: true})
+		if err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		if !reflect.DeepEqual(changes, wantChanges) {
+			t.Errorf("SyncDir() got changes = %v, want %v", changes, wantChanges)
+		}
+		if after, _ := DiffDirEntries(src, dest); !reflect.DeepEqual(before, after) {
+			t.Errorf("SyncDir() changed destination in dry run: %+v", after)
+		}
+	})
+
+	t.Run("Sync with delete", func(t *testing.T) {
+		root, src, dest := prepare(t)
+		defer os.RemoveAll(root)
+
+		changes, err := SyncDir(src, dest, &SyncOptions{Delete: true})
+		if err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		if !reflect.DeepEqual(changes, wantChanges) {
+			t.Errorf("SyncDir() got changes = %v, want %v", changes, wantChanges)
+		}
+		if diff, err := DiffDirEntries(src, dest); err != nil || !diff.IsEmpty() {
+			t.Errorf("SyncDir() got diff = %+v, error = %v, want no difference", diff, err)
+		}
+
+		// nothing to do for the second run
+		if changes, err = SyncDir(src, dest, &SyncOptions{Delete: true}); err != nil || len(changes) != 0 {
+			t.Errorf("SyncDir() second run got changes = %v, error = %v, want none", changes, err)
+		}
+	})
+
+	t.Run("Sync without delete", func(t *testing.T) {
+		root, src, dest := prepare(t)
+		defer os.RemoveAll(root)
+
+		if _, err := SyncDir(src, dest, nil); err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		diff, _ := DiffDirEntries(src, dest)
+		want := []string{"extra-dir", JoinPath("extra-dir", "d.txt"), JoinPath("nested", "extra.txt")}
+		if !reflect.DeepEqual(diff, &DirDiff{OnlyInRight: want}) {
+			t.Errorf("SyncDir() got diff = %+v, want only extraneous entries %v", diff, want)
+		}
+	})
+
+	t.Run("Sync with checksum", func(t *testing.T) {
+		root, src, dest := prepare(t)
+		defer os.RemoveAll(root)
+
+		changes, err := SyncDir(src, dest, &SyncOptions{Checksum: true, DryRun: true})
+		if err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		for _, c := range changes {
+			if c.Path == "touched.txt" {
+				t.Errorf("SyncDir() got change for file with the same content: %v", c)
+			}
+		}
+	})
+
+	t.Run("Missing destination", func(t *testing.T) {
+		root, src, _ := prepare(t)
+		defer os.RemoveAll(root)
+		dest := JoinPath(root, "missing")
+
+		changes, err := SyncDir(src, dest, &SyncOptions{CopyOptions: CopyOptions{Workers: 4}})
+		if err != nil {
+			t.Errorf("SyncDir() error = %v", err)
+			return
+		}
+		if want := []*SyncChange{{SyncCopy, "."}}; !reflect.DeepEqual(changes, want) {
+			t.Errorf("SyncDir() got changes = %v, want %v", changes, want)
+		}
+		if same, err := SameDirEntries(src, dest); err != nil || !same {
+			t.Errorf("SyncDir() got same = %v, error = %v, want same", same, err)
+		}
+	})
+}
+
+func TestSyncDir_Error(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"source/a.txt": "a",
+		"file.txt":     "file",
+	})
+	defer os.RemoveAll(root)
+	src := JoinPath(root, "source")
+
+	tests := []struct {
+		name string
+		src  string
+		dest string
+	}{
+		{"Source is empty", emptyStr, JoinPath(root, "dest")},
+		{"Destination is empty", src, emptyStr},
+		{"Source is not found", JoinPath(root, "__not_found__"), JoinPath(root, "dest")},
+		{"Source is a file", JoinPath(root, "file.txt"), JoinPath(root, "dest")},
+		{"Destination is a file", src, JoinPath(root, "file.txt")},
+		{"Destination is the source", src, src},
+		{"Parent of destination is not found", src, JoinPath(root, "__not_found__", "dest")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SyncDir(tt.src, tt.dest, nil); err == nil {
+				t.Errorf("SyncDir() got no error, want error")
+			} else {
+				expectedErrorCheck(t, err)
+			}
+		})
+	}
+}
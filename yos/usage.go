@@ -0,0 +1,166 @@
+package yos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DiskUsage represents the disk usage of a directory including all its descendants.
+type DiskUsage struct {
+	// Path is the path of the directory.
+	Path string
+	// ApparentSize is the total size in bytes of regular files and symbolic links.
+	ApparentSize int64
+	// AllocatedSize is the total size in bytes of blocks allocated for regular files and symbolic links, it's the same as ApparentSize if it's not supported on the platform.
+	AllocatedSize int64
+	// Files is the number of regular files and symbolic links counted.
+	Files int64
+	// Subdirs holds the disk usage of nested directories in lexical order.
+	Subdirs []*DiskUsage
+}
+
+// FileUsage represents the disk usage of a regular file or symbolic link.
+type FileUsage struct {
+	// Path is the path of the file.
+	Path string
+	// ApparentSize is the size in bytes of the file.
+	ApparentSize int64
+	// AllocatedSize is the size in bytes of blocks allocated for the file.
+	AllocatedSize int64
+}
+
+// DiskUsageReport represents the disk usage of a directory with the largest nested directories and files.
+type DiskUsageReport struct {
+	// Root is the disk usage of the given directory.
+	Root *DiskUsage
+	// LargestDirs holds the nested directories with the largest allocated sizes in descending order.
+	LargestDirs []*DiskUsage
+	// LargestFiles holds the files with the largest allocated sizes in descending order.
+	LargestFiles []*FileUsage
+}
+
+// DiskUsageOptions represents the options for GetDiskUsage. The zero value or nil indicates the default behavior.
+type DiskUsageOptions struct {
+	// WalkOptions is used for walking through the directory, but Flag, Patterns, MinDepth and MaxDepth are ignored since all entries are counted.
+	WalkOptions
+	// Top is the maximum number of the largest directories and files to report, zero indicates no such report.
+	Top int
+}
+
+// GetDiskUsage returns the disk usage of a directory with a breakdown for each nested directory like du.
+//
+// Regular files and symbolic links are counted, and files with multiple hard links are counted only once.
+// If the given path is a symbolic link, it will be followed, but symbolic links inside the directory won't unless FollowSymlinks is set.
+func GetDiskUsage(path string, opts *DiskUsageOptions) (report *DiskUsageReport, err error) {
+	return GetDiskUsageContext(context.Background(), path, opts)
+}
+
+// GetDiskUsageContext returns the disk usage of a directory with a breakdown for each nested directory with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func GetDiskUsageContext(ctx context.Context, path string, opts *DiskUsageOptions) (report *DiskUsageReport, err error) {
+	var (
+		root     string
+		walkOpts WalkOptions
+		top      int
+	)
+	if root, _, err = resolveDirInfo(path); err != nil {
+		return nil, opError(opnSize, path, err)
+	}
+	if opts != nil {
+		walkOpts, top = opts.WalkOptions, opts.Top
+	}
+	progress := newProgressTracker(opnSize, walkOpts.Progress)
+	walkOpts.Progress, walkOpts.Flag, walkOpts.Patterns, walkOpts.MinDepth, walkOpts.MaxDepth = nil, 0, nil, 0, 0
+
+	var (
+		rootUsage = &DiskUsage{Path: root}
+		dirs      = map[string]*DiskUsage{root: rootUsage}
+		inodes    = make(map[fileInode]bool)
+		files     []*FileUsage
+	)
+	err = walkCondEntries(ctx, root, &walkOpts, func(string, os.FileInfo) (bool, error) { return true, nil }, func(entry *FilePathInfo) error {
+		parent := dirs[filepath.Dir(entry.Path)]
+		if entry.Info.IsDir() {
+			usage := &DiskUsage{Path: entry.Path}
+			dirs[entry.Path] = usage
+			parent.Subdirs = append(parent.Subdirs, usage)
+			return nil
+		}
+
+		apparent, allocated := entry.Info.Size(), entry.Info.Size()
+		if st, ok := getFileSysStat(entry.Info); ok {
+			allocated = st.Blocks * 512
+			// count hard links only once
+			if st.Nlink > 1 {
+				key := fileInode{dev: st.Dev, ino: st.Ino}
+				if inodes[key] {
+					return nil
+				}
+				inodes[key] = true
+			}
+		}
+		parent.ApparentSize += apparent
+		parent.AllocatedSize += allocated
+		parent.Files++
+		if top > 0 {
+			files = append(files, &FileUsage{Path: entry.Path, ApparentSize: apparent, AllocatedSize: allocated})
+		}
+		progress.addFile(entry.Path, apparent)
+		return nil
+	})
+	if err != nil {
+		// return the partial result for errors collected
+		if _, ok := err.(PathErrors); !ok {
+			return nil, err
+		}
+	}
+
+	rootUsage.sum()
+	report = &DiskUsageReport{Root: rootUsage}
+	if top > 0 {
+		delete(dirs, root)
+		for _, usage := range dirs {
+			report.LargestDirs = append(report.LargestDirs, usage)
+		}
+		sort.Slice(report.LargestDirs, func(i, j int) bool {
+			a, b := report.LargestDirs[i], report.LargestDirs[j]
+			return largerUsage(a.AllocatedSize, b.AllocatedSize, a.ApparentSize, b.ApparentSize, a.Path, b.Path)
+		})
+		sort.Slice(files, func(i, j int) bool {
+			a, b := files[i], files[j]
+			return largerUsage(a.AllocatedSize, b.AllocatedSize, a.ApparentSize, b.ApparentSize, a.Path, b.Path)
+		})
+		if len(report.LargestDirs) > top {
+			report.LargestDirs = report.LargestDirs[:top]
+		}
+		if len(files) > top {
+			files = files[:top]
+		}
+		report.LargestFiles = files
+	}
+	return
+}
+
+// sum adds up the disk usage of nested directories recursively.
+func (d *DiskUsage) sum() {
+	for _, sub := range d.Subdirs {
+		sub.sum()
+		d.ApparentSize += sub.ApparentSize
+		d.AllocatedSize += sub.AllocatedSize
+		d.Files += sub.Files
+	}
+}
+
+// largerUsage indicates whether the first usage is larger than the second one, paths are compared for equal sizes to keep the order stable.
+func largerUsage(allocated1, allocated2, apparent1, apparent2 int64, path1, path2 string) bool {
+	if allocated1 != allocated2 {
+		return allocated1 > allocated2
+	}
+	if apparent1 != apparent2 {
+		return apparent1 > apparent2
+	}
+	return path1 < path2
+}
@@ -0,0 +1,66 @@
+package yos
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetDiskUsage(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":         strings.Repeat("a", 100),
+		"big/b.bin":     strings.Repeat("b", 200000),
+		"big/c.bin":     strings.Repeat("c", 50000),
+		"small/d.txt":   strings.Repeat("d", 10),
+		"small/e/f.txt": strings.Repeat("f", 20),
+		"link.txt":      "->a.txt",
+	})
+	defer os.RemoveAll(root)
+	if err := os.Link(JoinPath(root, "big", "b.bin"), JoinPath(root, "small", "hard.bin")); err != nil {
+		t.Skipf("Skipping for hard links not supported: %v", err)
+	}
+
+	report, err := GetDiskUsage(root, &DiskUsageOptions{Top: 2})
+	if err != nil {
+		t.Errorf("GetDiskUsage() error = %v", err)
+		return
+	}
+
+	// the hard link is counted once
+	usage := report.Root
+	wantApparent := int64(100 + 200000 + 50000 + 10 + 20 + len("a.txt"))
+	if usage.ApparentSize != wantApparent || usage.Files != 6 {
+		t.Errorf("GetDiskUsage() got apparent size = %d, files = %d, want %d, 6", usage.ApparentSize, usage.Files, wantApparent)
+	}
+	if usage.AllocatedSize < 200000 {
+		t.Errorf("GetDiskUsage() got allocated size = %d, want at least 200000", usage.AllocatedSize)
+	}
+
+	if len(usage.Subdirs) != 2 || usage.Subdirs[0].Path != JoinPath(root, "big") || usage.Subdirs[1].Path != JoinPath(root, "small") {
+		t.Errorf("GetDiskUsage() got unexpected subdirs: %v", usage.Subdirs)
+		return
+	}
+	if big := usage.Subdirs[0]; big.ApparentSize != 250000 || big.Files != 2 {
+		t.Errorf("GetDiskUsage() got big = %+v", big)
+	}
+	if small := usage.Subdirs[1]; small.ApparentSize != 30 || small.Files != 2 || len(small.Subdirs) != 1 || small.Subdirs[0].ApparentSize != 20 {
+		t.Errorf("GetDiskUsage() got small = %+v", small)
+	}
+
+	if len(report.LargestDirs) != 2 || report.LargestDirs[0].Path != JoinPath(root, "big") {
+		t.Errorf("GetDiskUsage() got largest dirs = %v", report.LargestDirs)
+	}
+	if len(report.LargestFiles) != 2 || report.LargestFiles[0].ApparentSize != 200000 || report.LargestFiles[1].Path != JoinPath(root, "big", "c.bin") {
+		t.Errorf("GetDiskUsage() got largest files = %v", report.LargestFiles)
+	}
+
+	// no top lists by default
+	if report, err = GetDiskUsage(root, nil); err != nil || report.LargestDirs != nil || report.LargestFiles != nil {
+		t.Errorf("GetDiskUsage() got = %+v, error = %v, want no top lists", report, err)
+	}
+	if _, err = GetDiskUsage(JoinPath(root, "a.txt"), nil); err == nil {
+		t.Errorf("GetDiskUsage() got no error for a file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+}
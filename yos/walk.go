@@ -0,0 +1,253 @@
+package yos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrStopWalk is used as a return value from WalkFunc to indicate that the walk should stop, it's not returned as an error by WalkEntries.
+var ErrStopWalk = errors.New("yos: stop walking")
+
+// WalkFunc is the type of the function called by WalkEntries for each matched entry.
+//
+// If the function returns ErrStopWalk, the walk stops and no error is returned, unless errors were collected before it with ContinueOnError. If it returns filepath.SkipDir for a directory, entries in the directory are skipped.
+// Any other error stops the walk and is returned.
+type WalkFunc func(entry *FilePathInfo) error
+
+// WalkOptions represents the options for operations walking through a directory. The zero value or nil indicates the default behavior.
+type WalkOptions struct {
+	// Progress is called after each entry is processed if it's not nil.
+	Progress ProgressFunc
+	// Flag is the combination of flags for ListMatch to filter entries for WalkEntries, zero indicates ListRecursive | ListIncludeAll.
+	Flag int
+	// Patterns holds the patterns for ListMatch to filter entries for WalkEntries, empty indicates entries with any name.
+	Patterns []string
+	// MinDepth is the minimum depth of entries to include for listing, entries directly in the root are at depth 1, and zero indicates no limit.
+	MinDepth int
+	// MaxDepth is the maximum depth of entries to walk through for listing, directories at the depth are not walked into, and zero indicates no limit.
+	MaxDepth int
+	// FollowSymlinks indicates whether to walk into directories and read files that symbolic links point to, entries under a followed link have paths under the link.
+	FollowSymlinks bool
+	// OnLinkIssue is called for each broken symbolic link or link cycle found while following symbolic links if it's not nil,
+	// and such links are treated as symbolic links without aborting the walk.
+	OnLinkIssue func(issue *LinkIssue)
+	// ContinueOnError indicates whether to skip entries that can't be read and continue walking, errors are collected and returned as PathErrors with the partial result.
+	ContinueOnError bool
+	// Exclude holds rules in .gitignore syntax relative to the root to exclude entries, excluded directories are not walked into.
+	Exclude []string
+	// IgnoreFileName is the name of ignore files like ".gitignore", rules in such files found in the root and nested directories are applied as well if it's not empty.
+	IgnoreFileName string
+}
+
+// WalkEntries walks through the given directory in lexical order, and calls the function for each entry matched with the options as soon as it's found.
+// The given directory is not included.
+//
+// Entries are filtered by Flag and Patterns in the options in the same way as ListMatch, and all entries are walked recursively if the options is nil.
+// Unlike the listing functions, entries are not accumulated in memory, and the walk can be stopped early by returning ErrStopWalk from the function.
+func WalkEntries(root string, opts *WalkOptions, fn WalkFunc) error {
+	return WalkEntriesContext(context.Background(), root, opts, fn)
+}
+
+// WalkEntriesContext walks through the given directory in lexical order with the context, and calls the function for each entry matched with the options.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func WalkEntriesContext(ctx context.Context, root string, opts *WalkOptions, fn WalkFunc) (err error) {
+	var (
+		flag     = ListRecursive | ListIncludeAll
+		patterns = []string{"*"}
+		progress *progressTracker
+		cond     funcEntryCond
+	)
+	if opts != nil {
+		if opts.Flag != 0 {
+			flag = opts.Flag
+		}
+		if len(opts.Patterns) > 0 {
+			patterns = opts.Patterns
+		} else {
+			// the default wildcard pattern doesn't work as a regular expression or for paths
+			flag &^= ListUseRegExp | ListMatchPath
+		}
+		progress = newProgressTracker(opnList, opts.Progress)
+	}
+	if cond, err = newMatchCond(flag, patterns); err != nil {
+		return
+	}
+
+	err = walkCondEntries(ctx, root, opts, cond, func(entry *FilePathInfo) (errFn error) {
+		errFn = fn(entry)
+		if !entry.Info.IsDir() {
+			progress.addFile(entry.Path, 0)
+		}
+		return
+	})
+	if err == ErrStopWalk {
+		err = nil
+	}
+	return
+}
+
+// ListEntries returns a list of entries matched with the options in the given directory in lexical order. The given directory is not included in the list.
+//
+// It filters entries in the same way as WalkEntries, and all entries are listed recursively if the options is nil.
+// It works as the other listing functions with more controls, e.g. &WalkOptions{Flag: ListRecursive | ListIncludeFile, MaxDepth: 2} lists files like ListFile within two levels.
+func ListEntries(root string, opts *WalkOptions) (entries []*FilePathInfo, err error) {
+	return ListEntriesContext(context.Background(), root, opts)
+}
+
+// ListEntriesContext returns a list of entries matched with the options in the given directory in lexical order with the context.
+//
+// It checks the context between entries, and returns the error of the context wrapped in *os.PathError if it's done.
+func ListEntriesContext(ctx context.Context, root string, opts *WalkOptions) (entries []*FilePathInfo, err error) {
+	err = WalkEntriesContext(ctx, root, opts, func(entry *FilePathInfo) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return
+}
+
+// LinkIssueKind represents the kind of problem found on a symbolic link while following symbolic links.
+type LinkIssueKind int
+
+const (
+	// LinkBroken indicates the target of the symbolic link doesn't exist or can't be accessed.
+	LinkBroken LinkIssueKind = iota
+	// LinkCycle indicates the symbolic link points to a directory containing itself, or symbolic links refer to each other in a loop.
+	LinkCycle
+)
+
+// String returns the name of the kind.
+func (k LinkIssueKind) String() string {
+	switch k {
+	case LinkBroken:
+		return "broken"
+	case LinkCycle:
+		return "cycle"
+	}
+	return "unknown"
+}
+
+// LinkIssue describes a symbolic link that can't be followed while walking through a directory.
+type LinkIssue struct {
+	// Kind is the kind of the problem.
+	Kind LinkIssueKind
+	// Path is the path of the symbolic link.
+	Path string
+	// Err is the error from resolving the symbolic link, it's nil for a link pointing to its ancestor directory.
+	Err error
+}
+
+// treeWalker walks through a directory like filepath.Walk, but follows symbolic links to directories and files.
+type treeWalker struct {
+	fn        filepath.WalkFunc
+	report    func(issue *LinkIssue)
+	ancestors []os.FileInfo
+}
+
+// walkTree walks through the directory like filepath.Walk, and symbolic links are followed if it's required.
+// Symbolic links that can't be followed are reported to the function if it's not nil, and visited as symbolic links.
+func walkTree(root string, follow bool, report func(issue *LinkIssue), fn filepath.WalkFunc) (err error) {
+	if !follow {
+		return filepath.Walk(root, fn)
+	}
+
+	w := &treeWalker{fn: fn, report: report}
+	var info os.FileInfo
+	if info, err = os.Stat(root); err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walk(root, info)
+	}
+	if err == filepath.SkipDir {
+		err = nil
+	}
+	return
+}
+
+// walk visits the entry and its descendants recursively, it's forked from walk() in path/filepath/path.go.
+func (w *treeWalker) walk(path string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return w.fn(path, info, nil)
+	}
+
+	names, err := readDirNames(path)
+	err1 := w.fn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	w.ancestors = append(w.ancestors, info)
+	defer func() {
+		w.ancestors = w.ancestors[:len(w.ancestors)-1]
+	}()
+
+	for _, name := range names {
+		fileName := filepath.Join(path, name)
+		fileInfo, err := os.Lstat(fileName)
+		if err != nil {
+			if err = w.fn(fileName, fileInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if isSymlinkFi(&fileInfo) {
+			fileInfo = w.resolveLink(fileName, fileInfo)
+		}
+		if err = w.walk(fileName, fileInfo); err != nil {
+			if !fileInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveLink returns the file info of the target of the symbolic link, or the link itself with the issue reported if it can't be followed.
+func (w *treeWalker) resolveLink(path string, linkInfo os.FileInfo) os.FileInfo {
+	var issue *LinkIssue
+	target, err := os.Stat(path)
+	switch {
+	case err != nil:
+		issue = &LinkIssue{Kind: LinkBroken, Path: path, Err: err}
+		if isLinkLoopError(err) {
+			issue.Kind = LinkCycle
+		}
+	case target.IsDir() && w.isAncestor(target):
+		issue = &LinkIssue{Kind: LinkCycle, Path: path}
+	default:
+		return target
+	}
+
+	if w.report != nil {
+		w.report(issue)
+	}
+	return linkInfo
+}
+
+// isAncestor indicates whether the directory is one of the directories being walked through.
+func (w *treeWalker) isAncestor(dir os.FileInfo) bool {
+	for _, ancestor := range w.ancestors {
+		if os.SameFile(ancestor, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// readDirNames reads the directory named by dirname and returns a sorted list of directory entries, it's forked from path/filepath/path.go.
+func readDirNames(dirname string) ([]string, error) {
+	f, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,109 @@
+//go:build !windows
+// +build !windows
+
+package yos
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWalkOptions_FollowSymlinks(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"shared/s.txt":    "shared",
+		"repo/a.txt":      "a",
+		"repo/lib":        "->../shared",
+		"repo/file.txt":   "->a.txt",
+		"repo/sub/up":     "->..",
+		"repo/broken":     "->missing",
+		"repo/loop":       "->loop",
+		"plain/a.txt":     "a",
+		"plain/lib/s.txt": "shared",
+		"plain/file.txt":  "a",
+		"plain/sub/up":    "->..",
+		"plain/broken":    "->missing",
+		"plain/loop":      "->loop",
+	})
+	defer os.RemoveAll(root)
+	repo := JoinPath(root, "repo")
+
+	var issues []string
+	opts := &WalkOptions{FollowSymlinks: true, OnLinkIssue: func(issue *LinkIssue) {
+		rel, _ := filepath.Rel(repo, issue.Path)
+		issues = append(issues, issue.Kind.String()+" "+filepath.ToSlash(rel))
+	}}
+
+	t.Run("ListEntries", func(t *testing.T) {
+		issues = nil
+		entries, err := ListEntries(repo, opts)
+		if err != nil {
+			t.Errorf("ListEntries() error = %v", err)
+			return
+		}
+		got := make(map[string]string)
+		for _, entry := range entries {
+			rel, _ := filepath.Rel(repo, entry.Path)
+			kind := "file"
+			if entry.Info.IsDir() {
+				kind = "dir"
+			} else if isSymlinkFi(&entry.Info) {
+				kind = "link"
+			}
+			got[filepath.ToSlash(rel)] = kind
+		}
+		want := map[string]string{
+			"a.txt":     "file",
+			"broken":    "link",
+			"file.txt":  "file",
+			"lib":       "dir",
+			"lib/s.txt": "file",
+			"loop":      "link",
+			"sub":       "dir",
+			"sub/up":    "link",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ListEntries() got = %v, want %v", got, want)
+		}
+		sort.Strings(issues)
+		if wantIssues := []string{"broken broken", "cycle loop", "cycle sub/up"}; !reflect.DeepEqual(issues, wantIssues) {
+			t.Errorf("ListEntries() got issues = %v, want %v", issues, wantIssues)
+		}
+	})
+
+	t.Run("Not following", func(t *testing.T) {
+		entries, err := ListEntries(repo, &WalkOptions{Flag: ListRecursive | ListIncludeDir})
+		if err != nil {
+			t.Errorf("ListEntries() error = %v", err)
+			return
+		}
+		if len(entries) != 1 || entries[0].Info.Name() != "sub" {
+			t.Errorf("ListEntries() got = %v, want only sub", entries)
+		}
+	})
+
+	t.Run("GetDirSizeWithOptions", func(t *testing.T) {
+		size, err := GetDirSizeWithOptions(repo, &WalkOptions{FollowSymlinks: true})
+		if err != nil {
+			t.Errorf("GetDirSizeWithOptions() error = %v", err)
+			return
+		}
+		// two files, a followed file link and three links can't be followed
+		want := int64(len("a")+len("shared")+len("a")) + int64(len("..")+len("missing")+len("loop"))
+		if size != want {
+			t.Errorf("GetDirSizeWithOptions() got = %v, want %v", size, want)
+		}
+	})
+
+	t.Run("SameDirEntriesWithOptions", func(t *testing.T) {
+		plain := JoinPath(root, "plain")
+		if same, err := SameDirEntriesWithOptions(repo, plain, &WalkOptions{FollowSymlinks: true}); err != nil || !same {
+			t.Errorf("SameDirEntriesWithOptions() got = %v, error = %v, want same", same, err)
+		}
+		if same, err := SameDirEntries(repo, plain); err != nil || same {
+			t.Errorf("SameDirEntries() got = %v, error = %v, want different", same, err)
+		}
+	})
+}
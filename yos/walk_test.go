@@ -0,0 +1,215 @@
+package yos
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalkEntries(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":          "a",
+		"b.log":          "b",
+		"link.txt":       "->a.txt",
+		"sub/c.txt":      "c",
+		"sub/d.log":      "d",
+		"sub/deep/e.TXT": "e",
+		"empty/":         emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name    string
+		opts    *WalkOptions
+		want    []string
+		wantErr bool
+	}{
+		{"Nil options", nil, []string{"a.txt", "b.log", "empty", "link.txt", "sub", "sub/c.txt", "sub/d.log", "sub/deep", "sub/deep/e.TXT"}, false},
+		{"Only files", &WalkOptions{Flag: ListRecursive | ListIncludeFile}, []string{"a.txt", "b.log", "sub/c.txt", "sub/d.log", "sub/deep/e.TXT"}, false},
+		{"Not recursive", &WalkOptions{Flag: ListIncludeAll}, []string{"a.txt", "b.log", "empty", "link.txt", "sub"}, false},
+		{"Wildcard patterns", &WalkOptions{Patterns: []string{"*.txt"}}, []string{"a.txt", "link.txt", "sub/c.txt"}, false},
+		{"Lower case", &WalkOptions{Flag: ListRecursive | ListToLower | ListIncludeFile, Patterns: []string{"*.txt"}}, []string{"a.txt", "sub/c.txt", "sub/deep/e.TXT"}, false},
+		{"Regular expression", &WalkOptions{Flag: ListRecursive | ListUseRegExp | ListIncludeFile, Patterns: []string{`^[a-c]\.`}}, []string{"a.txt", "b.log", "sub/c.txt"}, false},
+		{"Regular expression without patterns", &WalkOptions{Flag: ListRecursive | ListUseRegExp | ListIncludeDir}, []string{"empty", "sub", "sub/deep"}, false},
+		{"Malformed pattern", &WalkOptions{Flag: ListRecursive | ListUseRegExp | ListIncludeAll, Patterns: []string{"[a-"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := WalkEntries(root, tt.opts, func(entry *FilePathInfo) error {
+				rel, _ := filepath.Rel(root, entry.Path)
+				got = append(got, filepath.ToSlash(rel))
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WalkEntries() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			expectedErrorCheck(t, err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WalkEntries() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkEntries_Stop(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+		"sub/c.txt": "c",
+		"z.txt":     "z",
+	})
+	defer os.RemoveAll(root)
+	errCustom := errors.New("custom error")
+
+	tests := []struct {
+		name    string
+		ret     func(entry *FilePathInfo) error
+		want    []string
+		wantErr error
+	}{
+		{"Stop walking", func(entry *FilePathInfo) error {
+			if entry.Info.Name() == "b.txt" {
+				return ErrStopWalk
+			}
+			return nil
+		}, []string{"a.txt", "sub", "b.txt"}, nil},
+		{"Skip directory", func(entry *FilePathInfo) error {
+			if entry.Info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}, []string{"a.txt", "sub", "z.txt"}, nil},
+		{"Return error", func(entry *FilePathInfo) error {
+			if entry.Info.Name() == "sub" {
+				return errCustom
+			}
+			return nil
+		}, []string{"a.txt", "sub"}, errCustom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := WalkEntries(root, nil, func(entry *FilePathInfo) error {
+				got = append(got, entry.Info.Name())
+				return tt.ret(entry)
+			})
+			if err != tt.wantErr {
+				t.Errorf("WalkEntries() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WalkEntries() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkEntriesContext(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt": "a",
+	})
+	defer os.RemoveAll(root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := WalkEntriesContext(ctx, root, nil, func(entry *FilePathInfo) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WalkEntriesContext() got error = %v, want context canceled", err)
+		return
+	}
+	expectedErrorCheck(t, err)
+}
+
+func TestListEntries_Depth(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":          "a",
+		"d1/b.txt":       "b",
+		"d1/d2/c.txt":    "c",
+		"d1/d2/d3/d.txt": "d",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name string
+		opts *WalkOptions
+		want []string
+	}{
+		{"No limit", &WalkOptions{}, []string{"a.txt", "d1", "d1/b.txt", "d1/d2", "d1/d2/c.txt", "d1/d2/d3", "d1/d2/d3/d.txt"}},
+		{"Max depth 1", &WalkOptions{MaxDepth: 1}, []string{"a.txt", "d1"}},
+		{"Max depth 2", &WalkOptions{MaxDepth: 2}, []string{"a.txt", "d1", "d1/b.txt", "d1/d2"}},
+		{"Min depth 3", &WalkOptions{MinDepth: 3}, []string{"d1/d2/c.txt", "d1/d2/d3", "d1/d2/d3/d.txt"}},
+		{"Min depth 2 and max depth 3", &WalkOptions{MinDepth: 2, MaxDepth: 3}, []string{"d1/b.txt", "d1/d2", "d1/d2/c.txt", "d1/d2/d3"}},
+		{"Files within 2 levels", &WalkOptions{Flag: ListRecursive | ListIncludeFile, MaxDepth: 2}, []string{"a.txt", "d1/b.txt"}},
+		{"Min depth beyond the tree", &WalkOptions{MinDepth: 5}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ListEntries(root, tt.opts)
+			if err != nil {
+				t.Errorf("ListEntries() error = %v", err)
+				return
+			}
+			var got []string
+			for _, entry := range entries {
+				rel, _ := filepath.Rel(root, entry.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListEntries() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListEntries_SameAsList(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":         "a",
+		"b.json":        "b",
+		"d1/c.txt":      "c",
+		"d1/d2/":        "",
+		"d1/link.txt":   "->c.txt",
+		"d1/d2/link.go": "->../../a.txt",
+	})
+	defer os.RemoveAll(root)
+
+	tests := []struct {
+		name   string
+		listFn func(root string) ([]*FilePathInfo, error)
+		opts   *WalkOptions
+	}{
+		{"ListAll", ListAll, nil},
+		{"ListFile", ListFile, &WalkOptions{Flag: ListRecursive | ListIncludeFile}},
+		{"ListSymlink", ListSymlink, &WalkOptions{Flag: ListRecursive | ListIncludeSymlink}},
+		{"ListDir", ListDir, &WalkOptions{Flag: ListRecursive | ListIncludeDir}},
+		{"ListMatch", func(root string) ([]*FilePathInfo, error) {
+			return ListMatch(root, ListRecursive|ListIncludeFile|ListIncludeSymlink, "*.txt")
+		}, &WalkOptions{Flag: ListRecursive | ListIncludeFile | ListIncludeSymlink, Patterns: []string{"*.txt"}}},
+	}
+	paths := func(entries []*FilePathInfo) (got []string) {
+		for _, entry := range entries {
+			got = append(got, entry.Path)
+		}
+		return
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.listFn(root)
+			if err != nil {
+				t.Errorf("%s() error = %v", tt.name, err)
+				return
+			}
+			got, err := ListEntries(root, tt.opts)
+			if err != nil {
+				t.Errorf("ListEntries() error = %v", err)
+				return
+			}
+			if len(want) == 0 || !reflect.DeepEqual(paths(got), paths(want)) {
+				t.Errorf("ListEntries() got = %v, want %v", paths(got), paths(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package yos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWatchDebounce is the default window to coalesce events of the same path.
+	defaultWatchDebounce = 100 * time.Millisecond
+	// defaultWatchMaxWaitRatio is the default ratio of the maximum delay of pending events to the debounce window.
+	defaultWatchMaxWaitRatio = 10
+)
+
+// WatchOp represents the operations on an entry reported by Watcher, and multiple operations may be combined with bitwise OR.
+type WatchOp uint32
+
+// Operations reported by Watcher.
+const (
+	// WatchCreate indicates an entry is created or moved into the directory.
+	WatchCreate WatchOp = 1 << iota
+	// WatchWrite indicates content of a file is written.
+	WatchWrite
+	// WatchRemove indicates an entry is removed.
+	WatchRemove
+	// WatchRename indicates an entry is renamed or moved out of its directory, and the new path is reported with WatchCreate if it's still watched.
+	WatchRename
+	// WatchChmod indicates metadata of an entry like permissions, ownership or timestamps is changed.
+	WatchChmod
+)
+
+// String returns names of the operations separated by "|".
+func (op WatchOp) String() string {
+	var names []string
+	for _, item := range []struct {
+		op   WatchOp
+		name string
+	}{
+		{WatchCreate, "CREATE"},
+		{WatchWrite, "WRITE"},
+		{WatchRemove, "REMOVE"},
+		{WatchRename, "RENAME"},
+		{WatchChmod, "CHMOD"},
+	} {
+		if op&item.op != 0 {
+			names = append(names, item.name)
+		}
+	}
+	if len(names) == 0 {
+		return "NONE"
+	}
+	return strings.Join(names, "|")
+}
+
+// WatchEvent represents the changes of an entry in the watched directory.
+type WatchEvent struct {
+	// Path is the full path of the entry.
+	Path string
+	// Op is the operations on the entry, which are combined if they happen within the debounce window.
+	Op WatchOp
+}
+
+// String returns a brief description of the event.
+func (e WatchEvent) String() string {
+	return e.Op.String() + " " + e.Path
+}
+
+// WatchOptions represents the options for WatchDir. The zero value or nil indicates the default behavior.
+type WatchOptions struct {
+	// Flag and Patterns filter events with the same rules of ListMatch, and all events are reported if Patterns is empty.
+	// Entries of all types are included if no ListInclude flags is set, and ListRecursive is ignored since the directory is always watched recursively.
+	Flag     int
+	Patterns []string
+	// Debounce is the window to coalesce events of the same path, zero indicates 100ms and a negative value indicates reporting events without delay.
+	Debounce time.Duration
+	// MaxWait is the maximum delay of pending events since the first one, so events are still reported while changes keep coming within the debounce window.
+	// Zero indicates ten times the debounce window, and it's ignored if events are reported without delay.
+	MaxWait time.Duration
+}
+
+// Watcher reports changes of entries in a directory and its subdirectories.
+type Watcher struct {
+	// Events delivers events of changes, it's closed after the watcher is closed.
+	Events <-chan WatchEvent
+	// Errors delivers errors occurred while watching, it's closed after the watcher is closed.
+	Errors <-chan error
+
+	root      string
+	cond      funcEntryCond
+	debounce  time.Duration
+	maxWait   time.Duration
+	backend   *watchBackend
+	events    chan WatchEvent
+	errs      chan error
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// rawWatchEvent represents an event from the platform-specific backend.
+type rawWatchEvent struct {
+	path  string
+	op    WatchOp
+	isDir bool
+}
+
+// WatchDir starts watching changes of entries in the directory recursively, new subdirectories are watched once they're created.
+//
+// Events and errors should be received from the channels of Watcher until it's closed, otherwise the watcher will be blocked.
+// It's supported on Linux only, and an error is returned on the other platforms.
+func WatchDir(path string, opts *WatchOptions) (w *Watcher, err error) {
+	return WatchDirContext(context.Background(), path, opts)
+}
+
+// WatchDirContext starts watching changes of entries in the directory recursively with the context, and the watcher is closed once the context is done.
+func WatchDirContext(ctx context.Context, path string, opts *WatchOptions) (w *Watcher, err error) {
+	var root string
+	if root, _, err = resolveDirInfo(path); err != nil {
+		return nil, opError(opnWatch, path, err)
+	}
+	if err = contextError(ctx, opnWatch, path); err != nil {
+		return nil, err
+	}
+
+	w = &Watcher{
+		root:     root,
+		debounce: defaultWatchDebounce,
+		events:   make(chan WatchEvent),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	w.Events, w.Errors = w.events, w.errs
+	if opts != nil {
+		if opts.Debounce != 0 {
+			w.debounce = opts.Debounce
+		}
+		w.maxWait = opts.MaxWait
+		if len(opts.Patterns) > 0 {
+			if w.cond, err = newWatchCond(opts.Flag, opts.Patterns); err != nil {
+				return nil, opError(opnWatch, path, err)
+			}
+		}
+	}
+	if w.maxWait <= 0 {
+		w.maxWait = w.debounce * defaultWatchMaxWaitRatio
+	}
+	if w.backend, err = newWatchBackend(root); err != nil {
+		return nil, opError(opnWatch, path, err)
+	}
+
+	raw := make(chan []rawWatchEvent)
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		defer close(raw)
+		w.backend.run(raw, w.done, w.sendError)
+	}()
+	go func() {
+		defer w.wg.Done()
+		w.deliver(raw)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// Close stops watching and closes the channels, it's safe to call it multiple times.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if err := w.backend.close(); err != nil {
+			w.closeErr = opError(opnWatch, w.root, err)
+		}
+		w.wg.Wait()
+		close(w.events)
+		close(w.errs)
+	})
+	return w.closeErr
+}
+
+// newWatchCond returns the condition matching events with the flags and patterns of ListMatch.
+func newWatchCond(flag int, patterns []string) (cond funcEntryCond, err error) {
+	if flag&ListIncludeAll == 0 {
+		flag |= ListIncludeAll
+	}
+	if flag&(ListUseRegExp|ListMatchPath) == 0 {
+		// check patterns in advance, since errors of patterns can't be returned while watching
+		for _, pat := range patterns {
+			if _, err = filepath.Match(pat, emptyStr); err != nil {
+				return
+			}
+		}
+	}
+	return newMatchCond(flag, patterns)
+}
+
+// matches indicates whether the event satisfies the filters of the watcher.
+func (w *Watcher) matches(e rawWatchEvent) bool {
+	if w.cond == nil {
+		return true
+	}
+	rel, err := filepath.Rel(w.root, e.path)
+	if err != nil || rel == "." {
+		return true
+	}
+
+	info, err := os.Lstat(e.path)
+	if err != nil {
+		// the entry is gone, so guess the type from the event
+		mode := os.FileMode(0)
+		if e.isDir {
+			mode = os.ModeDir
+		}
+		info = &watchFileInfo{name: filepath.Base(e.path), mode: mode}
+	}
+	ok, _ := w.cond(filepath.ToSlash(rel), info)
+	return ok
+}
+
+// deliver filters and coalesces events from the backend, and sends them to the channel in the order of first occurrence.
+func (w *Watcher) deliver(raw <-chan []rawWatchEvent) {
+	var (
+		pending  []WatchEvent
+		index    = make(map[string]int)
+		timer    = time.NewTimer(time.Hour)
+		deadline time.Time
+	)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() bool {
+		for _, e := range pending {
+			select {
+			case w.events <- e:
+			case <-w.done:
+				return false
+			}
+		}
+		pending, index = pending[:0], make(map[string]int)
+		return true
+	}
+
+	for {
+		select {
+		case batch, ok := <-raw:
+			if !ok {
+				flush()
+				return
+			}
+			if len(pending) == 0 {
+				deadline = time.Now().Add(w.maxWait)
+			}
+			for _, e := range batch {
+				if !w.matches(e) {
+					continue
+				}
+				if idx, found := index[e.path]; found {
+					pending[idx].Op |= e.op
+				} else {
+					index[e.path] = len(pending)
+					pending = append(pending, WatchEvent{Path: e.path, Op: e.op})
+				}
+			}
+			if w.debounce < 0 {
+				if !flush() {
+					return
+				}
+			} else if len(pending) > 0 {
+				// wait for the quiet window after the latest event, but no later than the deadline since the first pending one
+				wait := w.debounce
+				if left := time.Until(deadline); left < wait {
+					wait = left
+				}
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(wait)
+			}
+		case <-timer.C:
+			if !flush() {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// sendError reports the error occurred while watching, and returns false if the watcher is closed.
+func (w *Watcher) sendError(path string, err error) bool {
+	select {
+	case w.errs <- opError(opnWatch, path, err):
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// watchFileInfo is a minimal implementation of os.FileInfo for entries that no longer exist.
+type watchFileInfo struct {
+	name string
+	mode os.FileMode
+}
+
+func (fi *watchFileInfo) Name() string       { return fi.name }
+func (fi *watchFileInfo) Size() int64        { return 0 }
+func (fi *watchFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *watchFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *watchFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *watchFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,168 @@
+package yos
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask is the mask of inotify events to watch for each directory.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF | syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+// watchBackend watches directories with inotify.
+type watchBackend struct {
+	root  string
+	fd    int
+	file  *os.File
+	paths map[int32]string
+}
+
+// newWatchBackend creates an inotify instance and adds watches for the directory and its subdirectories.
+func newWatchBackend(root string) (b *watchBackend, err error) {
+	var fd int
+	if fd, err = syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK); err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	// the non-blocking descriptor is added to the runtime poller, so closing it unblocks the reading
+	b = &watchBackend{
+		root:  root,
+		fd:    fd,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		paths: make(map[int32]string),
+	}
+	if err = b.addTree(root, nil); err != nil {
+		_ = b.file.Close()
+		return nil, err
+	}
+	return
+}
+
+// addTree adds watches for the directory and its subdirectories, and collects events for existing entries inside if it's not nil.
+func (b *watchBackend) addTree(dir string, created *[]rawWatchEvent) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// entries may be removed before they're watched
+			if os.IsNotExist(err) && path != b.root {
+				return nil
+			}
+			return err
+		}
+		if created != nil && path != dir {
+			*created = append(*created, rawWatchEvent{path: path, op: WatchCreate, isDir: info.IsDir()})
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(b.fd, path, inotifyMask)
+		if err != nil {
+			if (err == syscall.ENOENT || err == syscall.ENOTDIR) && path != b.root {
+				return filepath.SkipDir
+			}
+			return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+		}
+		b.paths[int32(wd)] = path
+		return nil
+	})
+}
+
+// removeTree removes watches for the directory and its subdirectories, which are moved out of the watched directory.
+func (b *watchBackend) removeTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range b.paths {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			_, _ = syscall.InotifyRmWatch(b.fd, uint32(wd))
+			delete(b.paths, wd)
+		}
+	}
+}
+
+// run reads and translates inotify events until the backend is closed.
+func (b *watchBackend) run(out chan<- []rawWatchEvent, done <-chan struct{}, report func(path string, err error) bool) {
+	buf := make([]byte, (syscall.SizeofInotifyEvent+syscall.NAME_MAX+1)*64)
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrClosed) || !report(b.root, err) {
+				return
+			}
+			continue
+		}
+
+		var batch []rawWatchEvent
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				if !report(b.root, errWatchOverflow) {
+					return
+				}
+				continue
+			}
+			dir, found := b.paths[event.Wd]
+			if !found {
+				continue
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(b.paths, event.Wd)
+				continue
+			}
+
+			path := dir
+			if event.Len > 0 {
+				name := bytes.TrimRight(buf[nameStart:offset], "\x00")
+				path = filepath.Join(dir, string(name))
+			}
+			batch = b.translate(batch, event.Mask, path, dir == b.root)
+		}
+
+		if len(batch) > 0 {
+			select {
+			case out <- batch:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// translate appends events for the inotify event mask, and updates watches for directories created or moved.
+func (b *watchBackend) translate(batch []rawWatchEvent, mask uint32, path string, isRoot bool) []rawWatchEvent {
+	isDir := mask&syscall.IN_ISDIR != 0
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchCreate, isDir: isDir})
+		if isDir {
+			// entries may be created inside before the directory is watched
+			_ = b.addTree(path, &batch)
+		}
+	case mask&syscall.IN_MODIFY != 0:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchWrite, isDir: isDir})
+	case mask&syscall.IN_DELETE != 0:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchRemove, isDir: isDir})
+	case mask&syscall.IN_MOVED_FROM != 0:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchRename, isDir: isDir})
+		if isDir {
+			b.removeTree(path)
+		}
+	case mask&syscall.IN_ATTRIB != 0:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchChmod, isDir: isDir})
+	case mask&syscall.IN_DELETE_SELF != 0 && isRoot:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchRemove, isDir: true})
+	case mask&syscall.IN_MOVE_SELF != 0 && isRoot:
+		batch = append(batch, rawWatchEvent{path: path, op: WatchRename, isDir: true})
+	}
+	return batch
+}
+
+// close closes the inotify instance and removes all watches.
+func (b *watchBackend) close() error {
+	return b.file.Close()
+}
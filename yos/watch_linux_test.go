@@ -0,0 +1,214 @@
+package yos
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// collectWatchEvents receives events until no more events arrive within the quiet period.
+func collectWatchEvents(t *testing.T, w *Watcher, quiet time.Duration) map[string]WatchOp {
+	got := make(map[string]WatchOp)
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return got
+			}
+			got[e.Path] |= e.Op
+		case err := <-w.Errors:
+			t.Errorf("Watcher got error = %v", err)
+		case <-time.After(quiet):
+			return got
+		}
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt":     "hello",
+		"b.log":     "log",
+		"sub/c.txt": "world",
+	})
+	defer os.RemoveAll(root)
+
+	w, err := WatchDir(root, &WatchOptions{Debounce: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("WatchDir() error = %v", err)
+	}
+	defer w.Close()
+
+	steps := []func() error{
+		func() error { return ioutil.WriteFile(JoinPath(root, "a.txt"), []byte("changed"), 0644) },
+		func() error { return os.Chmod(JoinPath(root, "b.log"), 0600) },
+		func() error { return os.Remove(JoinPath(root, "sub", "c.txt")) },
+		func() error { return os.Rename(JoinPath(root, "b.log"), JoinPath(root, "sub", "b.log")) },
+		func() error { return os.MkdirAll(JoinPath(root, "new", "deep"), 0755) },
+	}
+	for _, step := range steps {
+		if err = step(); err != nil {
+			t.Fatalf("failed to change the directory: %v", err)
+		}
+	}
+	got := collectWatchEvents(t, w, 300*time.Millisecond)
+	want := map[string]WatchOp{
+		JoinPath(root, "a.txt"):        WatchWrite,
+		JoinPath(root, "b.log"):        WatchChmod | WatchRename,
+		JoinPath(root, "sub", "c.txt"): WatchRemove,
+		JoinPath(root, "sub", "b.log"): WatchCreate,
+		JoinPath(root, "new"):          WatchCreate,
+		JoinPath(root, "new", "deep"):  WatchCreate,
+	}
+	for path, op := range want {
+		if got[path]&op != op {
+			t.Errorf("WatchDir() got %v for %s, want %v", got[path], path, op)
+		}
+	}
+
+	// new subdirectories are watched
+	if err = ioutil.WriteFile(JoinPath(root, "new", "deep", "d.txt"), []byte("deep"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	got = collectWatchEvents(t, w, 300*time.Millisecond)
+	if op := got[JoinPath(root, "new", "deep", "d.txt")]; op&WatchCreate == 0 {
+		t.Errorf("WatchDir() got events = %v, want creation in new subdirectory", got)
+	}
+
+	// channels are closed after closing
+	if err = w.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Errorf("Close() again error = %v", err)
+	}
+	if _, ok := <-w.Events; ok {
+		t.Errorf("Close() got events channel not closed")
+	}
+}
+
+func TestWatchDir_Filter(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"sub/": emptyStr,
+	})
+	defer os.RemoveAll(root)
+
+	w, err := WatchDir(root, &WatchOptions{Flag: ListIncludeFile, Patterns: []string{"*.txt"}, Debounce: -1})
+	if err != nil {
+		t.Fatalf("WatchDir() error = %v", err)
+	}
+	defer w.Close()
+
+	for _, name := range []string{"a.txt", "b.log", "sub/c.txt", "dir.txt/"} {
+		path := JoinPath(root, name)
+		if name[len(name)-1] == '/' {
+			err = os.Mkdir(path, 0755)
+		} else {
+			err = ioutil.WriteFile(path, []byte(name), 0644)
+		}
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	if err = os.Remove(JoinPath(root, "a.txt")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	got := collectWatchEvents(t, w, 300*time.Millisecond)
+	want := map[string]WatchOp{
+		JoinPath(root, "a.txt"):        WatchCreate | WatchWrite | WatchRemove,
+		JoinPath(root, "sub", "c.txt"): WatchCreate | WatchWrite,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchDir() got events = %v, want %v", got, want)
+	}
+}
+
+func TestWatchDir_MaxWait(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt": "hello",
+	})
+	defer os.RemoveAll(root)
+
+	w, err := WatchDir(root, &WatchOptions{Debounce: 100 * time.Millisecond, MaxWait: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("WatchDir() error = %v", err)
+	}
+	defer w.Close()
+
+	// keep writing within the debounce window for much longer than the maximum delay
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				_ = ioutil.WriteFile(JoinPath(root, "a.txt"), []byte(time.Now().String()), 0644)
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	start := time.Now()
+	select {
+	case e := <-w.Events:
+		if e.Path != JoinPath(root, "a.txt") || e.Op&WatchWrite == 0 {
+			t.Errorf("WatchDir() got event = %v, want writing of a.txt", e)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("WatchDir() got the first event after %v, want it within the maximum delay", elapsed)
+		}
+	case err := <-w.Errors:
+		t.Errorf("Watcher got error = %v", err)
+	case <-time.After(3 * time.Second):
+		t.Errorf("WatchDir() got no events while the file keeps changing")
+	}
+}
+
+func TestWatchDirContext(t *testing.T) {
+	root := makeTestTree(t, map[string]string{
+		"a.txt": "hello",
+	})
+	defer os.RemoveAll(root)
+
+	if _, err := WatchDir(JoinPath(root, "a.txt"), nil); err == nil {
+		t.Errorf("WatchDir() got no error for a file")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+	if _, err := WatchDir(root, &WatchOptions{Patterns: []string{"[z-"}}); err == nil {
+		t.Errorf("WatchDir() got no error for malformed patterns")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := WatchDirContext(ctx, root, nil)
+	if err != nil {
+		t.Fatalf("WatchDirContext() error = %v", err)
+	}
+	cancel()
+	select {
+	case _, ok := <-w.Events:
+		if ok {
+			t.Errorf("WatchDirContext() got events after the context is canceled")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("WatchDirContext() got watcher not closed after the context is canceled")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = WatchDirContext(ctx, root, nil); err == nil {
+		t.Errorf("WatchDirContext() got no error for a canceled context")
+	} else {
+		expectedErrorCheck(t, err)
+	}
+}
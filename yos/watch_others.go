@@ -0,0 +1,20 @@
+// +build !linux
+
+package yos
+
+// watchBackend is a placeholder since watching is not supported on this platform.
+type watchBackend struct{}
+
+// newWatchBackend returns an error since watching is not supported on this platform.
+func newWatchBackend(root string) (b *watchBackend, err error) {
+	return nil, errWatchNotSupported
+}
+
+// run does nothing since watching is not supported on this platform.
+func (b *watchBackend) run(out chan<- []rawWatchEvent, done <-chan struct{}, report func(path string, err error) bool) {
+}
+
+// close does nothing since watching is not supported on this platform.
+func (b *watchBackend) close() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package yos
+
+import (
+	"testing"
+)
+
+func TestWatchOp_String(t *testing.T) {
+	tests := []struct {
+		op   WatchOp
+		want string
+	}{
+		{0, "NONE"},
+		{WatchCreate, "CREATE"},
+		{WatchWrite, "WRITE"},
+		{WatchRemove, "REMOVE"},
+		{WatchRename, "RENAME"},
+		{WatchChmod, "CHMOD"},
+		{WatchCreate | WatchWrite | WatchChmod, "CREATE|WRITE|CHMOD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.op.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got, want := (WatchEvent{Path: "/tmp/a.txt", Op: WatchRemove}).String(), "REMOVE /tmp/a.txt"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func Test_newWatchCond(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     int
+		patterns []string
+		wantErr  bool
+	}{
+		{"Glob", 0, []string{"*.txt"}, false},
+		{"Malformed glob", 0, []string{"[z-"}, true},
+		{"Regular expression", ListUseRegExp, []string{`\.txt$`}, false},
+		{"Malformed regular expression", ListUseRegExp, []string{`(`}, true},
+		{"Path glob", ListMatchPath, []string{"**/*.txt"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newWatchCond(tt.flag, tt.patterns); (err != nil) != tt.wantErr {
+				t.Errorf("newWatchCond() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}